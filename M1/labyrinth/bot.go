@@ -0,0 +1,227 @@
+package labyrinth
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"sort"
+)
+
+// 机器人策略
+const (
+	StrategyExplore = "explore" // 优先走访问次数最少的格子
+	StrategyChase   = "chase"   // 靠近最近的其他玩家
+	StrategyFlee    = "flee"    // 远离最近的其他玩家
+	StrategyRandom  = "random"  // 随机走
+)
+
+// Strategy 机器人每一步的决策，返回按优先级排列的可走方向
+type Strategy interface {
+	Name() string
+	Choose(labyrinth *Labyrinth, playerID byte, from Position) []string
+}
+
+// NewStrategy 按名称创建策略
+func NewStrategy(name string, rng *rand.Rand) (Strategy, error) {
+	switch name {
+	case StrategyExplore:
+		return &exploreStrategy{rng: rng, visits: make(map[Position]int)}, nil
+	case StrategyChase:
+		return &distanceStrategy{name: name, rng: rng, closer: true}, nil
+	case StrategyFlee:
+		return &distanceStrategy{name: name, rng: rng}, nil
+	case StrategyRandom:
+		return &randomStrategy{rng: rng}, nil
+	}
+	return nil, fmt.Errorf("unknown strategy %q", name)
+}
+
+type randomStrategy struct {
+	rng *rand.Rand
+}
+
+func (s *randomStrategy) Name() string { return StrategyRandom }
+
+func (s *randomStrategy) Choose(labyrinth *Labyrinth, playerID byte, from Position) []string {
+	moves := legalMoves(labyrinth, playerID, from)
+	s.rng.Shuffle(len(moves), func(i, j int) { moves[i], moves[j] = moves[j], moves[i] })
+	return directionNames(moves)
+}
+
+// exploreStrategy 记住本次运行中走过的格子
+type exploreStrategy struct {
+	rng    *rand.Rand
+	visits map[Position]int
+}
+
+func (s *exploreStrategy) Name() string { return StrategyExplore }
+
+func (s *exploreStrategy) Choose(labyrinth *Labyrinth, playerID byte, from Position) []string {
+	s.visits[from]++
+	moves := legalMoves(labyrinth, playerID, from)
+	s.rng.Shuffle(len(moves), func(i, j int) { moves[i], moves[j] = moves[j], moves[i] })
+	sortMoves(moves, func(d Direction) int {
+		next := Position{from.Row + d.DRow, from.Col + d.DCol}
+		if labyrinth.Map[next.Row][next.Col] == ExitTile {
+			return -1
+		}
+		return s.visits[next]
+	})
+	return directionNames(moves)
+}
+
+// distanceStrategy 根据到其他玩家的迷宫距离决定靠近或远离
+type distanceStrategy struct {
+	name   string
+	rng    *rand.Rand
+	closer bool
+}
+
+func (s *distanceStrategy) Name() string { return s.name }
+
+func (s *distanceStrategy) Choose(labyrinth *Labyrinth, playerID byte, from Position) []string {
+	moves := legalMoves(labyrinth, playerID, from)
+	s.rng.Shuffle(len(moves), func(i, j int) { moves[i], moves[j] = moves[j], moves[i] })
+	positions := PlayerPositions(labyrinth)
+	var others []Position
+	for _, id := range ActivePlayers(labyrinth) {
+		if id != playerID {
+			others = append(others, positions[id])
+		}
+	}
+	if len(others) == 0 {
+		return directionNames(moves)
+	}
+	dist := distanceField(labyrinth, playerID, others)
+	sortMoves(moves, func(d Direction) int {
+		i := cellIndex(labyrinth, Position{from.Row + d.DRow, from.Col + d.DCol})
+		if dist[i] < 0 {
+			// 到不了其他玩家的格子：追逐时最不优先，逃跑时最优先
+			if s.closer {
+				return len(dist)
+			}
+			return -len(dist)
+		}
+		if s.closer {
+			return int(dist[i])
+		}
+		return -int(dist[i])
+	})
+	return directionNames(moves)
+}
+
+// legalMoves 当前可以走的方向；tag 模式下可以走进其他玩家的格子
+// 站在楼梯上时楼梯也作为一个方向，偏移量为到另一端的行差
+func legalMoves(labyrinth *Labyrinth, playerID byte, from Position) []Direction {
+	tag := labyrinth.Game != nil && labyrinth.Game.Rules.Mode == ModeTag
+	candidates := Directions[:len(Directions):len(Directions)]
+	if link, name, ok := StairLink(labyrinth, from, TileUnder(labyrinth, playerID)); ok {
+		candidates = append(candidates, Direction{name, link.Row - from.Row, 0})
+	}
+	var moves []Direction
+	for _, d := range candidates {
+		row, col := from.Row+d.DRow, from.Col+d.DCol
+		if row < 0 || row >= labyrinth.Rows || col < 0 || col >= labyrinth.Cols {
+			continue
+		}
+		ch := labyrinth.Map[row][col]
+		if IsEmptySpace(labyrinth, row, col) ||
+			(IsDoor(ch) && HasKey(labyrinth, playerID, DoorKey(ch))) ||
+			(tag && IsPlayerTile(ch) && ch != playerID) {
+			moves = append(moves, d)
+		}
+	}
+	return moves
+}
+
+// distanceField 从 sources 出发的 BFS 距离，按 cellIndex 编号，-1 表示到不了
+func distanceField(labyrinth *Labyrinth, playerID byte, sources []Position) []int32 {
+	dist := make([]int32, labyrinth.Rows*labyrinth.Cols)
+	for i := range dist {
+		dist[i] = -1
+	}
+	queue := make([]Position, 0, len(sources))
+	for _, p := range sources {
+		dist[cellIndex(labyrinth, p)] = 0
+		queue = append(queue, p)
+	}
+	var buf [5]Position
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		for _, next := range neighbors(labyrinth, cur, buf[:0]) {
+			if next.Row < 0 || next.Row >= labyrinth.Rows || next.Col < 0 || next.Col >= labyrinth.Cols {
+				continue
+			}
+			ch := labyrinth.Map[next.Row][next.Col]
+			if ch == '#' || (IsDoor(ch) && !HasKey(labyrinth, playerID, DoorKey(ch))) || dist[cellIndex(labyrinth, next)] >= 0 {
+				continue
+			}
+			dist[cellIndex(labyrinth, next)] = dist[cellIndex(labyrinth, cur)] + 1
+			queue = append(queue, next)
+		}
+	}
+	return dist
+}
+
+// sortMoves 按 score 从小到大稳定排序，保留洗牌后的相对顺序以随机打破平局
+func sortMoves(moves []Direction, score func(Direction) int) {
+	sort.SliceStable(moves, func(i, j int) bool { return score(moves[i]) < score(moves[j]) })
+}
+
+func directionNames(moves []Direction) []string {
+	names := make([]string, len(moves))
+	for i, d := range moves {
+		names[i] = d.Name
+	}
+	return names
+}
+
+// ErrBotDone 机器人无法继续移动（比赛结束、到达出口或被抓）
+var ErrBotDone = errors.New("bot is done")
+
+// BotStep 在持有地图锁的情况下走一步，与命令行移动使用相同的锁、连通性检查与日志
+// 返回 false 表示还没轮到该玩家
+func BotStep(mapFile string, playerID byte, strategy Strategy) (*MoveEvent, bool, error) {
+	lock, err := LockMap(mapFile)
+	if err != nil {
+		return nil, false, err
+	}
+	defer lock.Unlock()
+	game := &Game{}
+	if err := game.Load(mapFile); err != nil {
+		return nil, false, err
+	}
+	labyrinth := game.Labyrinth()
+	if labyrinth.Finished[playerID] {
+		return nil, false, ErrBotDone
+	}
+	if state := labyrinth.Game; state != nil {
+		if state.Over || state.Captured[playerID] {
+			return nil, false, ErrBotDone
+		}
+		if state.Rules.TurnOrder && CurrentTurn(labyrinth) != playerID {
+			return nil, false, nil
+		}
+	}
+	from, err := FindPlayer(labyrinth, playerID)
+	if err != nil {
+		return nil, false, err
+	}
+
+	// 失败的移动会恢复地图与规则状态，候选方向直接在地图上依次尝试
+	err = errors.New("no legal move")
+	var event MoveEvent
+	for _, direction := range strategy.Choose(labyrinth, playerID, *from) {
+		if event, err = game.Move(playerID, direction); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	if err := game.Save(); err != nil {
+		return nil, false, err
+	}
+	return &event, true, nil
+}
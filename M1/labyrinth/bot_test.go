@@ -0,0 +1,93 @@
+package labyrinth
+
+import (
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestStrategies 测试各策略的方向选择
+func TestStrategies(t *testing.T) {
+	lab := newTestLabyrinth(
+		"1...0...",
+	)
+	rng := rand.New(rand.NewPCG(1, 1))
+	from := Position{0, 4}
+
+	chase, _ := NewStrategy(StrategyChase, rng)
+	if got := chase.Choose(lab, '0', from); len(got) != 2 || got[0] != "left" {
+		t.Errorf("chase = %v, expected left first", got)
+	}
+	flee, _ := NewStrategy(StrategyFlee, rng)
+	if got := flee.Choose(lab, '0', from); len(got) != 2 || got[0] != "right" {
+		t.Errorf("flee = %v, expected right first", got)
+	}
+
+	explore, _ := NewStrategy(StrategyExplore, rng)
+	explore.Choose(lab, '0', Position{0, 5})
+	if got := explore.Choose(lab, '0', from); got[0] != "left" {
+		t.Errorf("explore = %v, expected the unvisited cell first", got)
+	}
+
+	if _, err := NewStrategy("teleport", rng); err == nil {
+		t.Error("NewStrategy() should reject unknown strategies")
+	}
+}
+
+// TestBotStep 测试机器人通过地图文件移动并遵守轮流规则
+func TestBotStep(t *testing.T) {
+	mapFile := filepath.Join(t.TempDir(), "map.txt")
+	if err := os.WriteFile(mapFile, []byte("0..\n#.#\n1..\n@rules free turns\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	rng := rand.New(rand.NewPCG(1, 1))
+	strategy, _ := NewStrategy(StrategyRandom, rng)
+
+	if _, ok, err := BotStep(mapFile, '1', strategy); err != nil || ok {
+		t.Errorf("BotStep() for player 1 = %v, %v, expected to wait for player 0", ok, err)
+	}
+	entry, ok, err := BotStep(mapFile, '0', strategy)
+	if err != nil || !ok {
+		t.Fatalf("BotStep() = %v, %v", ok, err)
+	}
+	if entry.To != (Position{0, 1}) {
+		t.Errorf("BotStep() moved to %v, expected (0, 1)", entry.To)
+	}
+	lab := &Labyrinth{}
+	if err := LoadMap(lab, mapFile); err != nil {
+		t.Fatalf("LoadMap() error: %v", err)
+	}
+	if lab.Version != 1 || CurrentTurn(lab) != '1' {
+		t.Errorf("after BotStep() version=%d turn=%c, expected 1 and 1", lab.Version, CurrentTurn(lab))
+	}
+	if entries, err := ReadJournal(JournalPath(mapFile)); err != nil || len(entries) != 1 {
+		t.Errorf("ReadJournal() = %d entries, %v, expected 1", len(entries), err)
+	}
+}
+
+// fixedStrategy 按给定顺序尝试方向
+type fixedStrategy []string
+
+func (s fixedStrategy) Name() string { return "fixed" }
+
+func (s fixedStrategy) Choose(*Labyrinth, byte, Position) []string { return s }
+
+// TestBotStepFailedCandidate 测试失败的候选方向不影响保存的地图
+func TestBotStepFailedCandidate(t *testing.T) {
+	mapFile := filepath.Join(t.TempDir(), "map.txt")
+	// 玩家 1 站在传送门上，另一端被玩家 2 占住，向右抓人会失败
+	if err := os.WriteFile(mapFile, []byte("01.2\n....\n@rules tag\n@under 1 *\n@under 2 *\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	if _, ok, err := BotStep(mapFile, '0', fixedStrategy{"right", "down"}); err != nil || !ok {
+		t.Fatalf("BotStep() = %v, %v", ok, err)
+	}
+	lab := &Labyrinth{}
+	if err := LoadMap(lab, mapFile); err != nil {
+		t.Fatalf("LoadMap() error: %v", err)
+	}
+	if lab.Game.Captured['1'] || lab.Game.Scores['0'] != 0 || string(lab.Map[0]) != ".1.2" {
+		t.Errorf("after BotStep() map = %q, captured = %v, scores = %v", string(lab.Map[0]), lab.Game.Captured, lab.Game.Scores)
+	}
+}
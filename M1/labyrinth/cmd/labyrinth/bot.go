@@ -0,0 +1,66 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	"labyrinth"
+)
+
+// runBot 处理 labyrinth bot 子命令
+func runBot(args []string) int {
+	fs := flag.NewFlagSet("bot", flag.ContinueOnError)
+	mapFile := fs.String("map", "", "Map file path")
+	mapFileShort := fs.String("m", "", "Map file path (short)")
+	playerID := fs.String("player", "", "Player ID (0-9)")
+	playerIDShort := fs.String("p", "", "Player ID (short)")
+	strategyName := fs.String("strategy", labyrinth.StrategyExplore, "Strategy (explore/chase/flee/random)")
+	turns := fs.Int("turns", 10, "Number of moves to make")
+	seed := fs.Uint64("seed", uint64(time.Now().UnixNano()), "Random seed")
+	delay := fs.Duration("delay", 0, "Pause between moves")
+	if err := fs.Parse(args); err != nil || fs.NArg() > 0 || *turns < 0 {
+		printUsage()
+		return 1
+	}
+	if *mapFileShort != "" {
+		mapFile = mapFileShort
+	}
+	if *playerIDShort != "" {
+		playerID = playerIDShort
+	}
+	if *mapFile == "" || len(*playerID) != 1 || !labyrinth.IsValidPlayer(*playerID) {
+		printUsage()
+		return 1
+	}
+	rng := rand.New(rand.NewPCG(*seed, *seed))
+	strategy, err := labyrinth.NewStrategy(*strategyName, rng)
+	if err != nil {
+		printUsage()
+		return 1
+	}
+
+	id := byte((*playerID)[0])
+	for moved := 0; moved < *turns; {
+		entry, ok, err := labyrinth.BotStep(*mapFile, id, strategy)
+		if errors.Is(err, labyrinth.ErrBotDone) {
+			fmt.Printf("player %c: done after %d move(s)\n", id, moved)
+			return 0
+		}
+		if err != nil {
+			fmt.Println("Error:", err)
+			return 1
+		}
+		if !ok {
+			// 轮流模式下等待其他玩家
+			time.Sleep(max(*delay, 100*time.Millisecond))
+			continue
+		}
+		moved++
+		fmt.Printf("player %c (%s): %s -> (%d,%d)\n", id, strategy.Name(), entry.Direction, entry.To.Row, entry.To.Col)
+		time.Sleep(*delay)
+	}
+	return 0
+}
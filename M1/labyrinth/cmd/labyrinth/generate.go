@@ -0,0 +1,48 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"labyrinth"
+)
+
+// runGenerate 处理 labyrinth generate 子命令
+func runGenerate(args []string) int {
+	fs := flag.NewFlagSet("generate", flag.ContinueOnError)
+	rows := fs.Int("rows", 11, "Number of rows")
+	cols := fs.Int("cols", 11, "Number of columns")
+	seed := fs.Uint64("seed", 1, "Random seed")
+	density := fs.Float64("density", 0.5, "Target wall density (0-1)")
+	players := fs.Int("players", 2, "Number of players (0-10)")
+	algo := fs.String("algo", labyrinth.AlgoBacktracker, "Algorithm (backtracker/prim/cave)")
+	out := fs.String("out", "", "Output map file (default stdout)")
+	if err := fs.Parse(args); err != nil || fs.NArg() > 0 {
+		printUsage()
+		return 1
+	}
+
+	lab, err := labyrinth.Generate(labyrinth.GenerateOptions{
+		Rows:      *rows,
+		Cols:      *cols,
+		Seed:      *seed,
+		Density:   *density,
+		Players:   *players,
+		Algorithm: *algo,
+	})
+	if err != nil {
+		fmt.Println("Error generating map:", err)
+		return 1
+	}
+	if *out == "" {
+		for i := 0; i < lab.Rows; i++ {
+			fmt.Println(string(lab.Map[i]))
+		}
+		return 0
+	}
+	if err := labyrinth.SaveMap(lab, *out); err != nil {
+		fmt.Println("Error saving map:", err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+
+	"labyrinth"
+)
+
+// runHTTP 处理 labyrinth http 子命令：HTTP/JSON 接口
+func runHTTP(args []string) int {
+	fs := flag.NewFlagSet("http", flag.ContinueOnError)
+	mapFile := fs.String("map", "", "Map file path")
+	mapFileShort := fs.String("m", "", "Map file path (short)")
+	listen := fs.String("listen", ":8081", "Listen address")
+	if err := fs.Parse(args); err != nil || fs.NArg() > 0 {
+		printUsage()
+		return 1
+	}
+	if *mapFileShort != "" {
+		mapFile = mapFileShort
+	}
+	if *mapFile == "" {
+		printUsage()
+		return 1
+	}
+
+	server, err := labyrinth.NewHTTPServer(*mapFile)
+	if err != nil {
+		fmt.Println("Error loading map:", err)
+		return 1
+	}
+	ln, err := net.Listen("tcp", *listen)
+	if err != nil {
+		fmt.Println("Error listening:", err)
+		return 1
+	}
+	fmt.Printf("Labyrinth HTTP API listening on %s\n", ln.Addr())
+	if err := http.Serve(ln, server); err != nil {
+		fmt.Println("Error serving:", err)
+		return 1
+	}
+	return 0
+}
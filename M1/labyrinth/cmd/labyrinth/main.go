@@ -0,0 +1,274 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"labyrinth"
+)
+
+func main() {
+	// 子命令
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "bot":
+			os.Exit(runBot(os.Args[2:]))
+		case "play":
+			os.Exit(runPlay(os.Args[2:]))
+		case "serve":
+			os.Exit(runServe(os.Args[2:]))
+		case "http":
+			os.Exit(runHTTP(os.Args[2:]))
+		case "generate":
+			os.Exit(runGenerate(os.Args[2:]))
+		case "replay":
+			os.Exit(runReplay(os.Args[2:]))
+		case "rules":
+			os.Exit(runRules(os.Args[2:]))
+		case "status":
+			os.Exit(runStatus(os.Args[2:]))
+		case "validate":
+			os.Exit(runValidate(os.Args[2:]))
+		}
+	}
+
+	os.Exit(runMove(os.Args[1:]))
+}
+
+// runMove 处理默认命令：查询、移动、寻路、视野与撤销
+func runMove(args []string) int {
+	fs := flag.NewFlagSet("labyrinth", flag.ContinueOnError)
+	mapFile := fs.String("map", "", "Map file path")
+	mapFileShort := fs.String("m", "", "Map file path (short)")
+	playerID := fs.String("player", "1", "Player ID (0-9)")
+	playerIDShort := fs.String("p", "", "Player ID (short)")
+	moveDir := fs.String("move", "up", "Move direction (up/down/left/right/upstairs/downstairs)")
+	moves := fs.String("moves", "", "Comma separated moves applied atomically, run:DIR repeats until blocked")
+	movesFile := fs.String("moves-file", "", "Read --moves from a file")
+	pathTo := fs.String("path-to", "", "Print the shortest path to ROW,COL")
+	view := fs.Bool("view", false, "Print the map as seen by the player")
+	radius := fs.Int("radius", labyrinth.DefaultViewRadius, "View radius for --view")
+	undo := fs.Bool("undo", false, "Undo the last move")
+	redo := fs.Bool("redo", false, "Redo the last undone move")
+	format := fs.String("format", "text", "Output format: text or json")
+	version := fs.Bool("version", false, "Show version information")
+
+	// 先从原始参数中找出 --format json，参数解析失败时也要输出 JSON
+	out := &reporter{json: jsonRequested(args)}
+	if out.json {
+		fs.SetOutput(io.Discard)
+	}
+	if err := fs.Parse(args); err != nil {
+		return out.badArgs(err)
+	}
+
+	// 处理 --version
+	if *version {
+		if fs.NArg() > 0 || len(args) > 1 {
+			return out.usage()
+		}
+		fmt.Println(labyrinth.VersionInfo)
+		return exitOK
+	}
+
+	if *format != "text" && *format != "json" {
+		return out.badArgs(fmt.Errorf("invalid --format %q", *format))
+	}
+
+	// 合并短参数和长参数
+	if *mapFileShort != "" {
+		mapFile = mapFileShort
+	}
+	if *playerIDShort != "" {
+		playerID = playerIDShort
+	}
+
+	// 检查未知参数
+	if fs.NArg() > 0 {
+		return out.usage()
+	}
+
+	// 1. 验证参数
+	if *mapFile == "" || *playerID == "" || len(*playerID) != 1 || *moveDir == "" {
+		return out.usage()
+	}
+	if !labyrinth.IsValidPlayer(*playerID) {
+		return out.usage()
+	}
+	// --view 的输出带颜色，没有 JSON 形式
+	if *view && out.json {
+		return out.usage()
+	}
+	// --move、--moves 与 --moves-file 只能指定一个
+	batch, err := parseBatch(*moves, *movesFile)
+	if err != nil {
+		return out.fail(exitBadArgs, "Error parsing moves", err)
+	}
+	if batch != nil && flagSet(fs, "move") {
+		return out.usage()
+	}
+	// 2. 加载地图，整个 读取-移动-保存 过程都持有锁
+	lock, err := labyrinth.LockMap(*mapFile)
+	if err != nil {
+		return out.fail(exitLoadError, "Error locking map", err)
+	}
+	defer lock.Unlock()
+	game := &labyrinth.Game{}
+	err = game.Load(*mapFile)
+	if errors.Is(err, labyrinth.ErrNotConnected) {
+		return out.fail(exitNotConnected, "Error checking connectivity", err)
+	}
+	if err != nil {
+		return out.fail(exitLoadError, "Error loading map", err)
+	}
+	lab := game.Labyrinth()
+	if *undo || *redo {
+		var entry *labyrinth.JournalEntry
+		if *undo {
+			entry, err = game.Undo()
+		} else {
+			entry, err = game.Redo()
+		}
+		if err != nil {
+			return out.fail(exitInvalidMove, "Error", err)
+		}
+		if err = game.Save(); err != nil {
+			return out.fail(exitSaveError, "Error saving map", err)
+		}
+		out.text("%s\n", entry)
+		from, to := entry.From, entry.To
+		if *undo {
+			from, to = to, from
+		}
+		out.result.Player = string(entry.Player)
+		out.result.From, out.result.To = &from, &to
+		out.state(game, entry.Player)
+		return out.done()
+	}
+	// 3. 处理玩家查询或移动
+	playerid := byte((*playerID)[0])
+	out.result.Player = *playerID
+	postion, err := labyrinth.FindPlayer(lab, playerid)
+	if err != nil {
+		return out.fail(exitInvalidMove, "Error finding player", err)
+	}
+	out.result.From = postion
+	if *view {
+		memoryFile := labyrinth.FogPath(*mapFile, playerid)
+		memory, err := labyrinth.LoadMemory(memoryFile)
+		if err != nil {
+			return out.fail(exitLoadError, "Error loading view memory", err)
+		}
+		visible := labyrinth.VisibleCells(lab, *postion, *radius)
+		memory = labyrinth.UpdateMemory(lab, visible, memory)
+		fmt.Print(labyrinth.RenderView(lab, visible, memory))
+		if err = labyrinth.SaveMemory(memoryFile, memory); err != nil {
+			return out.fail(exitSaveError, "Error saving view memory", err)
+		}
+		return exitOK
+	}
+	out.text("Player found at (%d, %d)", postion.Row, postion.Col)
+	if *pathTo != "" {
+		target, err := labyrinth.ParsePosition(*pathTo)
+		if err != nil {
+			return out.fail(exitBadArgs, "Error parsing target", err)
+		}
+		var path []labyrinth.PathStep
+		if lab.Rows*lab.Cols > labyrinth.AStarThreshold {
+			path, err = labyrinth.AStarPath(lab, *postion, target)
+		} else {
+			path, err = labyrinth.ShortestPath(lab, *postion, target)
+		}
+		if err != nil {
+			return out.fail(exitInvalidMove, "Error finding path", err)
+		}
+		directions := labyrinth.PathDirections(path)
+		out.text("\npath: %s\n", strings.Join(directions, ","))
+		out.result.Path = directions
+		out.state(game, playerid)
+		return out.done()
+	}
+	if batch != nil {
+		events, err := game.MoveAll(playerid, batch)
+		if err != nil {
+			out.state(game, playerid)
+			return out.fail(exitInvalidMove, "Error moving player", err)
+		}
+		out.text("\n")
+		for i, event := range events {
+			out.text("step %d: %s -> (%d,%d)\n", i+1, event.Direction, event.To.Row, event.To.Col)
+			out.result.Steps = append(out.result.Steps, jsonStep{Direction: event.Direction, To: event.To})
+		}
+		last := events[len(events)-1].To
+		out.text("player new position at (%d,%d)\n", last.Row, last.Col)
+		if err = game.Save(); err != nil {
+			return out.fail(exitSaveError, "Error saving map", err)
+		}
+		out.result.To = &last
+		out.state(game, playerid)
+		return out.done()
+	}
+	event, err := game.Move(playerid, *moveDir)
+	if err != nil {
+		out.state(game, playerid)
+		return out.fail(exitInvalidMove, "Error moving player", err)
+	}
+	out.text("player new position at (%d,%d)\n", event.To.Row, event.To.Col)
+	// 4. 保存地图（如果有移动），地图被其他进程改过时拒绝这次移动
+	if err = game.Save(); err != nil {
+		return out.fail(exitSaveError, "Error saving map", err)
+	}
+	out.result.To = &event.To
+	out.state(game, playerid)
+	return out.done()
+}
+
+// parseBatch 解析 --moves 或 --moves-file，都没有指定时返回 nil
+func parseBatch(moves, movesFile string) ([]string, error) {
+	switch {
+	case moves != "" && movesFile != "":
+		return nil, errors.New("--moves and --moves-file are mutually exclusive")
+	case moves != "":
+		return labyrinth.ParseMoves(moves)
+	case movesFile != "":
+		return labyrinth.ReadMovesFile(movesFile)
+	}
+	return nil, nil
+}
+
+// flagSet 命令行中是否显式给出了该参数
+func flagSet(fs *flag.FlagSet, name string) bool {
+	set := false
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
+
+func printUsage() {
+	fmt.Println("Usage:")
+	fmt.Println("  labyrinth --map map.txt --player id")
+	fmt.Println("  labyrinth -m map.txt -p id")
+	fmt.Println("  labyrinth --map map.txt --player id --move direction")
+	fmt.Println("  labyrinth --map map.txt --player id --moves up,up,run:left | --moves-file moves.txt")
+	fmt.Println("  labyrinth --map map.txt --player id --path-to row,col")
+	fmt.Println("  labyrinth --map map.txt --player id --view [--radius N]")
+	fmt.Println("  labyrinth --map map.txt --undo | --redo")
+	fmt.Println("  labyrinth ... --format text|json")
+	fmt.Println("  labyrinth --version")
+	fmt.Println("  labyrinth generate --rows R --cols C --seed S --density D --players N [--algo backtracker|prim|cave] [--out file]")
+	fmt.Println("  labyrinth replay journal.log [--speed N] [--map map.txt]")
+	fmt.Println("  labyrinth rules --map map.txt --mode free|race|tag [--turns] [--limit N]")
+	fmt.Println("  labyrinth status --map map.txt [--format text|json]")
+	fmt.Println("  labyrinth validate --map map.txt [--json]")
+	fmt.Println("  labyrinth bot --map map.txt --player id --strategy explore|chase|flee|random --turns N [--seed S] [--delay D]")
+	fmt.Println("  labyrinth play --map map.txt --player id [--interval D]")
+	fmt.Println("  labyrinth serve --map map.txt --listen :PORT")
+	fmt.Println("  labyrinth http --map map.txt --listen :8081")
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"labyrinth"
+)
+
+// captureStdout 执行 fn 并返回它写到标准输出的内容
+func captureStdout(t *testing.T, fn func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	fn()
+	os.Stdout = stdout
+	w.Close()
+	out, _ := io.ReadAll(r)
+	return string(out)
+}
+
+// TestRunMoveExitCodes 测试每种失败对应不同的退出码与 error.code
+func TestRunMoveExitCodes(t *testing.T) {
+	dir := t.TempDir()
+	writeMap := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	ok := writeMap("ok.txt", "#####\n#0.1#\n#####\n")
+	split := writeMap("split.txt", "#######\n#0.#..#\n#######\n")
+
+	cases := []struct {
+		name string
+		args []string
+		code int
+	}{
+		{"bad args", []string{"-m", ok, "-p", "x"}, exitBadArgs},
+		{"unknown flag", []string{"-m", ok, "-p", "0", "--bogus"}, exitBadArgs},
+		{"missing flag value", []string{"-m", ok, "-p", "0", "--radius", "far"}, exitBadArgs},
+		{"load error", []string{"-m", filepath.Join(dir, "missing.txt"), "-p", "0"}, exitLoadError},
+		{"not connected", []string{"-m", split, "-p", "0", "--move", "right"}, exitNotConnected},
+		{"invalid move", []string{"-m", ok, "-p", "0", "--move", "up"}, exitInvalidMove},
+		{"ok", []string{"-m", ok, "-p", "0", "--move", "right"}, exitOK},
+	}
+	for _, c := range cases {
+		var code int
+		out := captureStdout(t, func() {
+			code = runMove(append(c.args, "--format", "json"))
+		})
+		if code != c.code {
+			t.Errorf("%s: exit code = %d, expected %d", c.name, code, c.code)
+		}
+		var result moveResult
+		if err := json.Unmarshal([]byte(out), &result); err != nil {
+			t.Fatalf("%s: output is not JSON: %v\n%s", c.name, err, out)
+		}
+		if c.code == exitOK {
+			if !result.OK || result.Error != nil {
+				t.Errorf("%s: result = %+v, expected ok", c.name, result)
+			}
+		} else if result.Error == nil || result.Error.Code != errorCodes[c.code] {
+			t.Errorf("%s: error = %+v, expected code %s", c.name, result.Error, errorCodes[c.code])
+		}
+	}
+}
+
+// TestRunMoveJSON 测试 JSON 输出包含新旧位置、整张地图与其他玩家
+func TestRunMoveJSON(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "map.txt")
+	if err := os.WriteFile(path, []byte("#####\n#0..#\n#..1#\n#####\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var code int
+	out := captureStdout(t, func() {
+		code = runMove([]string{"-m", path, "-p", "0", "--moves", "right,down", "--format", "json"})
+	})
+	if code != exitOK {
+		t.Fatalf("exit code = %d\n%s", code, out)
+	}
+	var result moveResult
+	if err := json.Unmarshal([]byte(out), &result); err != nil {
+		t.Fatal(err)
+	}
+	if result.Player != "0" || *result.From != (labyrinth.Position{Row: 1, Col: 1}) || *result.To != (labyrinth.Position{Row: 2, Col: 2}) {
+		t.Errorf("result player/from/to = %s %+v %+v", result.Player, result.From, result.To)
+	}
+	if len(result.Steps) != 2 || result.Steps[1].Direction != "down" {
+		t.Errorf("result steps = %+v", result.Steps)
+	}
+	if len(result.Map) != 4 || result.Map[2] != "#.01#" {
+		t.Errorf("result map = %q", result.Map)
+	}
+	if len(result.Players) != 1 || result.Players[0].ID != "1" || result.Players[0].Position != (labyrinth.Position{Row: 2, Col: 3}) {
+		t.Errorf("result players = %+v", result.Players)
+	}
+}
+
+// TestRunStatusJSON 测试 status --format json 只读取状态，不移动玩家也不写文件
+func TestRunStatusJSON(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "map.txt")
+	content := "#####\n#0..#\n#..1#\n#####\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var code int
+	out := captureStdout(t, func() {
+		code = runStatus([]string{"--map", path, "--format", "json"})
+	})
+	if code != exitOK {
+		t.Fatalf("exit code = %d\n%s", code, out)
+	}
+	var result moveResult
+	if err := json.Unmarshal([]byte(out), &result); err != nil {
+		t.Fatal(err)
+	}
+	if !result.OK || len(result.Map) != 4 || result.Map[1] != "#0..#" || len(result.Players) != 2 {
+		t.Errorf("result = %+v", result)
+	}
+	if saved, _ := os.ReadFile(path); string(saved) != content {
+		t.Errorf("status changed the map: %q", saved)
+	}
+	if _, err := os.Stat(path + labyrinth.JournalSuffix); !os.IsNotExist(err) {
+		t.Errorf("status wrote a journal: %v", err)
+	}
+
+	out = captureStdout(t, func() {
+		code = runStatus([]string{"--map", filepath.Join(t.TempDir(), "missing.txt"), "--format", "json"})
+	})
+	if err := json.Unmarshal([]byte(out), &result); err != nil || code != exitLoadError || result.Error == nil || result.Error.Code != "load_error" {
+		t.Errorf("missing map: exit %d, output %s", code, out)
+	}
+}
+
+// TestJSONRequested 测试在 flag 解析前识别 --format json
+func TestJSONRequested(t *testing.T) {
+	cases := []struct {
+		args []string
+		json bool
+	}{
+		{[]string{"--format", "json"}, true},
+		{[]string{"-format=json", "--bogus"}, true},
+		{[]string{"--bogus", "--format", "json"}, true},
+		{[]string{"--format", "text"}, false},
+		{[]string{"--map", "format", "json"}, false},
+		{[]string{"--", "--format", "json"}, false},
+	}
+	for _, c := range cases {
+		if got := jsonRequested(c.args); got != c.json {
+			t.Errorf("jsonRequested(%q) = %v, expected %v", c.args, got, c.json)
+		}
+	}
+
+	// 请求过 JSON 时，后面的 --format 写错同样输出 bad_args
+	var code int
+	out := captureStdout(t, func() {
+		code = runMove([]string{"--format", "json", "--format", "xml"})
+	})
+	var result moveResult
+	if err := json.Unmarshal([]byte(out), &result); err != nil || code != exitBadArgs ||
+		result.Error == nil || result.Error.Code != "bad_args" {
+		t.Errorf("runMove(--format xml) = %d, %q", code, out)
+	}
+}
+
+// TestRunRulesArchivesJournal 测试规则重置后撤销与回放不会越过重置
+func TestRunRulesArchivesJournal(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "map.txt")
+	if err := os.WriteFile(path, []byte("#####\n#0..#\n#####\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	run := func(fn func([]string) int, args ...string) int {
+		var code int
+		captureStdout(t, func() { code = fn(args) })
+		return code
+	}
+	if code := run(runMove, "-m", path, "-p", "0", "--move", "right"); code != exitOK {
+		t.Fatalf("move: exit %d", code)
+	}
+	if code := run(runRules, "--map", path, "--mode", "race", "--limit", "5"); code != 0 {
+		t.Fatalf("rules: exit %d", code)
+	}
+	if _, err := os.Stat(labyrinth.JournalPath(path) + ".v1"); err != nil {
+		t.Errorf("journal before the rules change was not archived: %v", err)
+	}
+	if code := run(runMove, "-m", path, "--undo"); code != exitInvalidMove {
+		t.Errorf("undo across the rules change: exit %d, expected %d", code, exitInvalidMove)
+	}
+
+	if code := run(runMove, "-m", path, "-p", "0", "--move", "right"); code != exitOK {
+		t.Fatalf("move: exit %d", code)
+	}
+	if code := run(runMove, "-m", path, "--undo"); code != exitOK {
+		t.Fatalf("undo: exit %d", code)
+	}
+	lab := &labyrinth.Labyrinth{}
+	if err := labyrinth.LoadMap(lab, path); err != nil {
+		t.Fatal(err)
+	}
+	if string(lab.Map[1]) != "#.0.#" || lab.Game.Moves['0'] != 0 || lab.Game.Scores['0'] != 0 {
+		t.Errorf("after undo map = %q, moves %v, scores %v, expected #.0.# with no moves", lab.Map[1], lab.Game.Moves, lab.Game.Scores)
+	}
+	if code := run(runReplay, labyrinth.JournalPath(path), "--speed", "0"); code != 0 {
+		t.Errorf("replay after the rules change: exit %d", code)
+	}
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"labyrinth"
+)
+
+// 退出码，每种失败一个；--format json 时 error.code 给出同样的分类
+const (
+	exitOK           = 0
+	exitBadArgs      = 1
+	exitLoadError    = 2
+	exitNotConnected = 3
+	exitInvalidMove  = 4
+	exitSaveError    = 5
+)
+
+// errorCodes 退出码对应的 error.code
+var errorCodes = map[int]string{
+	exitBadArgs:      "bad_args",
+	exitLoadError:    "load_error",
+	exitNotConnected: "not_connected",
+	exitInvalidMove:  "invalid_move",
+	exitSaveError:    "save_error",
+}
+
+// moveResult --format json 的输出，前端不需要再解析文本
+type moveResult struct {
+	OK      bool                `json:"ok"`
+	Player  string              `json:"player,omitempty"`
+	From    *labyrinth.Position `json:"from,omitempty"`
+	To      *labyrinth.Position `json:"to,omitempty"`
+	Steps   []jsonStep          `json:"steps,omitempty"`
+	Path    []string            `json:"path,omitempty"`
+	Map     []string            `json:"map,omitempty"`
+	Players []jsonPlayer        `json:"players,omitempty"`
+	Error   *jsonError          `json:"error,omitempty"`
+}
+
+// jsonStep 批量移动中的一步
+type jsonStep struct {
+	Direction string             `json:"direction"`
+	To        labyrinth.Position `json:"to"`
+}
+
+// jsonPlayer 其他玩家的位置，已被抓的玩家位置为 -1,-1
+type jsonPlayer struct {
+	ID string `json:"id"`
+	labyrinth.Position
+	Finished bool `json:"finished,omitempty"`
+	Captured bool `json:"captured,omitempty"`
+}
+
+type jsonError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// reporter 按 --format 输出结果，text 模式保持原来的输出
+type reporter struct {
+	json   bool
+	result moveResult
+}
+
+// text 只在 text 模式下输出
+func (r *reporter) text(format string, args ...any) {
+	if !r.json {
+		fmt.Printf(format, args...)
+	}
+}
+
+// jsonRequested 参数中是否有 --format json（也接受 -format 与 =json 的写法），
+// 在 flag 解析之前判断，解析失败时同样输出 JSON
+func jsonRequested(args []string) bool {
+	for i, arg := range args {
+		if arg == "--" {
+			break
+		}
+		name, value, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if !strings.HasPrefix(arg, "-") || name != "format" {
+			continue
+		}
+		if !hasValue && i+1 < len(args) {
+			value = args[i+1]
+		}
+		if value == "json" {
+			return true
+		}
+	}
+	return false
+}
+
+// usage 参数错误
+func (r *reporter) usage() int {
+	return r.badArgs(errors.New("invalid arguments"))
+}
+
+// badArgs 参数错误，text 模式打印用法，json 模式输出 bad_args 与具体原因
+func (r *reporter) badArgs(err error) int {
+	if !r.json {
+		printUsage()
+		return exitBadArgs
+	}
+	return r.fail(exitBadArgs, "", err)
+}
+
+// fail 报告错误并返回对应的退出码，text 模式输出 "context: err"
+func (r *reporter) fail(code int, context string, err error) int {
+	if !r.json {
+		fmt.Println(context+":", err)
+		return code
+	}
+	r.result.OK = false
+	r.result.Error = &jsonError{Code: errorCodes[code], Message: err.Error()}
+	r.print()
+	return code
+}
+
+// done 报告成功
+func (r *reporter) done() int {
+	if r.json {
+		r.result.OK = true
+		r.print()
+	}
+	return exitOK
+}
+
+// state 记录地图与其他玩家的位置
+func (r *reporter) state(game *labyrinth.Game, playerID byte) {
+	r.result.Map = strings.Split(strings.TrimSuffix(game.Render(), "\n"), "\n")
+	r.result.Players = nil
+	for _, player := range game.Players() {
+		if player.ID == playerID {
+			continue
+		}
+		r.result.Players = append(r.result.Players, jsonPlayer{
+			ID:       string(player.ID),
+			Position: player.Position,
+			Finished: player.Finished,
+			Captured: player.Captured,
+		})
+	}
+}
+
+func (r *reporter) print() {
+	out, _ := json.MarshalIndent(r.result, "", "  ")
+	fmt.Println(string(out))
+}
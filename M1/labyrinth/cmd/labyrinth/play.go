@@ -0,0 +1,267 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	"labyrinth"
+)
+
+const (
+	ansiClear      = "\033[H\033[2J"
+	ansiHideCursor = "\033[?25l"
+	ansiShowCursor = "\033[?25h"
+	ansiAltScreen  = "\033[?1049h"
+	ansiMainScreen = "\033[?1049l"
+	ansiWall       = "\033[2m"
+	ansiBold       = "\033[1m"
+	ansiReset      = "\033[0m"
+)
+
+// playerColors 每个玩家一种前景色，按 ID 取
+var playerColors = []string{"31", "32", "33", "34", "35", "36", "91", "92", "93", "94"}
+
+// runPlay 处理 labyrinth play 子命令：全屏交互模式
+func runPlay(args []string) int {
+	fs := flag.NewFlagSet("play", flag.ContinueOnError)
+	mapFile := fs.String("map", "", "Map file path")
+	mapFileShort := fs.String("m", "", "Map file path (short)")
+	playerID := fs.String("player", "", "Player ID (0-9)")
+	playerIDShort := fs.String("p", "", "Player ID (short)")
+	interval := fs.Duration("interval", 200*time.Millisecond, "How often to check the map for changes")
+	if err := fs.Parse(args); err != nil || fs.NArg() > 0 || *interval <= 0 {
+		printUsage()
+		return 1
+	}
+	if *mapFileShort != "" {
+		mapFile = mapFileShort
+	}
+	if *playerIDShort != "" {
+		playerID = playerIDShort
+	}
+	if *mapFile == "" || len(*playerID) != 1 || !labyrinth.IsValidPlayer(*playerID) {
+		printUsage()
+		return 1
+	}
+	id := byte((*playerID)[0])
+
+	game := &labyrinth.Game{}
+	if err := game.Load(*mapFile); err != nil {
+		fmt.Println("Error loading map:", err)
+		return 1
+	}
+	stamp, _ := mapStamp(*mapFile)
+
+	fd := int(os.Stdin.Fd())
+	state, err := makeRaw(fd)
+	if err != nil {
+		fmt.Println("Error: play needs an interactive terminal:", err)
+		return 1
+	}
+	// 正常退出、出错与收到信号时都要恢复终端
+	defer func() {
+		fmt.Print(ansiShowCursor + ansiMainScreen)
+		restoreTerminal(fd, state)
+	}()
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, append([]os.Signal{syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP}, resizeSignals...)...)
+	defer signal.Stop(signals)
+	fmt.Print(ansiAltScreen + ansiHideCursor)
+
+	keys := make(chan string)
+	go readKeys(os.Stdin, keys)
+	ticker := time.NewTicker(*interval)
+	defer ticker.Stop()
+
+	status := "arrows/WASD to move, < > for stairs, r to reload, q to quit"
+	redraw := true
+	for {
+		if redraw {
+			rows, cols, err := terminalSize(fd)
+			if err != nil || rows == 0 || cols == 0 {
+				rows, cols = 24, 80
+			}
+			fmt.Print(renderScreen(game.Labyrinth(), id, rows, cols, status))
+			redraw = false
+		}
+
+		select {
+		case sig := <-signals:
+			if slices.Contains(resizeSignals, sig) {
+				redraw = true
+				continue
+			}
+			return 1
+		case <-ticker.C:
+			// 其他进程提交了移动时重新加载
+			current, err := mapStamp(*mapFile)
+			if err != nil || current == stamp {
+				continue
+			}
+			stamp = current
+			if err := game.Load(*mapFile); err != nil {
+				status = "reload failed: " + err.Error()
+			}
+			redraw = true
+		case key, ok := <-keys:
+			if !ok || key == "quit" {
+				return 0
+			}
+			redraw = true
+			if key == "reload" {
+				if err := game.Load(*mapFile); err != nil {
+					status = "reload failed: " + err.Error()
+				}
+				continue
+			}
+			if err := playMove(game, *mapFile, id, key); err != nil {
+				status = "cannot move " + key + ": " + err.Error()
+			} else {
+				status = "moved " + key
+			}
+			stamp, _ = mapStamp(*mapFile)
+		}
+	}
+}
+
+// playMove 与命令行移动相同：持锁加载最新地图，移动后提交
+func playMove(game *labyrinth.Game, mapFile string, playerID byte, direction string) error {
+	lock, err := labyrinth.LockMap(mapFile)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+	if err := game.Load(mapFile); err != nil {
+		return err
+	}
+	if _, err := game.Move(playerID, direction); err != nil {
+		// 丢弃这次失败的修改
+		if loadErr := game.Load(mapFile); loadErr != nil {
+			return errors.Join(err, loadErr)
+		}
+		return err
+	}
+	return game.Save()
+}
+
+// mapStamp 地图文件的修改时间与大小，用于发现其他进程的修改
+func mapStamp(mapFile string) (string, error) {
+	info, err := os.Stat(mapFile)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d:%d", info.ModTime().UnixNano(), info.Size()), nil
+}
+
+// readKeys 把按键翻译成方向或命令，stdin 关闭时关闭 keys
+func readKeys(in *os.File, keys chan<- string) {
+	defer close(keys)
+	buf := make([]byte, 64)
+	for {
+		n, err := in.Read(buf)
+		if err != nil {
+			return
+		}
+		for _, key := range parseKeys(buf[:n]) {
+			keys <- key
+		}
+	}
+}
+
+// parseKeys 解析一次读取到的按键：方向键的转义序列、WASD、< >（上下楼）、r、q 与 Ctrl-C
+func parseKeys(input []byte) []string {
+	var keys []string
+	for i := 0; i < len(input); i++ {
+		switch ch := input[i]; {
+		case ch == 0x1b && i+2 < len(input) && input[i+1] == '[':
+			switch input[i+2] {
+			case 'A':
+				keys = append(keys, "up")
+			case 'B':
+				keys = append(keys, "down")
+			case 'C':
+				keys = append(keys, "right")
+			case 'D':
+				keys = append(keys, "left")
+			}
+			i += 2
+		case ch == 'w' || ch == 'W':
+			keys = append(keys, "up")
+		case ch == 's' || ch == 'S':
+			keys = append(keys, "down")
+		case ch == 'a' || ch == 'A':
+			keys = append(keys, "left")
+		case ch == 'd' || ch == 'D':
+			keys = append(keys, "right")
+		case ch == '<':
+			keys = append(keys, labyrinth.DirectionUpstairs)
+		case ch == '>':
+			keys = append(keys, labyrinth.DirectionDownstairs)
+		case ch == 'r' || ch == 'R':
+			keys = append(keys, "reload")
+		case ch == 'q' || ch == 'Q' || ch == 0x03:
+			keys = append(keys, "quit")
+		}
+	}
+	return keys
+}
+
+// renderScreen 绘制一帧：以玩家为中心的视口、状态行与提示行
+// raw 模式下没有输出处理，换行需要写 \r\n
+func renderScreen(lab *labyrinth.Labyrinth, playerID byte, height, width int, status string) string {
+	var sb strings.Builder
+	sb.WriteString(ansiClear)
+	viewRows, viewCols := max(height-2, 1), max(width, 1)
+	top, left := 0, 0
+	if p, err := labyrinth.FindPlayer(lab, playerID); err == nil {
+		top = viewportStart(p.Row, lab.Rows, viewRows)
+		left = viewportStart(p.Col, lab.Cols, viewCols)
+	}
+	for i := top; i < min(top+viewRows, lab.Rows); i++ {
+		for j := left; j < min(left+viewCols, lab.Cols); j++ {
+			ch := lab.Map[i][j]
+			switch {
+			case labyrinth.IsPlayerTile(ch):
+				style := "\033[" + playerColors[ch-'0'] + "m"
+				if ch == playerID {
+					style = ansiBold + "\033[7;" + playerColors[ch-'0'] + "m"
+				}
+				sb.WriteString(style)
+				sb.WriteByte(ch)
+				sb.WriteString(ansiReset)
+			case ch == '#':
+				sb.WriteString(ansiWall + "#" + ansiReset)
+			default:
+				sb.WriteByte(ch)
+			}
+		}
+		sb.WriteString("\r\n")
+	}
+	info := fmt.Sprintf("player %c", playerID)
+	if p, err := labyrinth.FindPlayer(lab, playerID); err == nil && lab.Map[p.Row][p.Col] == playerID {
+		info += fmt.Sprintf(" at (%d, %d)", p.Row, p.Col)
+	}
+	if keys := lab.Inventory[playerID]; keys != "" {
+		info += ", keys " + keys
+	}
+	if lab.Finished[playerID] {
+		info += ", reached the exit"
+	}
+	sb.WriteString(info + "\r\n" + status)
+	return sb.String()
+}
+
+// viewportStart 视口起点，让 pos 尽量居中且不越过地图边界
+func viewportStart(pos, size, view int) int {
+	if size <= view {
+		return 0
+	}
+	return min(max(pos-view/2, 0), size-view)
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"labyrinth"
+)
+
+// TestParseKeys 测试方向键、WASD 与退出键的解析
+func TestParseKeys(t *testing.T) {
+	got := parseKeys([]byte("\x1b[A\x1b[Dwsad<>rq\x03"))
+	want := []string{"up", "left", "up", "down", "left", "right", "upstairs", "downstairs", "reload", "quit", "quit"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseKeys() = %v, expected %v", got, want)
+	}
+}
+
+// TestRenderScreen 测试视口跟随玩家并高亮当前玩家
+func TestRenderScreen(t *testing.T) {
+	lab := &labyrinth.Labyrinth{Rows: 1, Cols: 10, Map: [][]byte{[]byte("1.......0#")}}
+	screen := renderScreen(lab, '0', 3, 4, "status")
+	if strings.Contains(screen, "\033[32m1") {
+		t.Error("renderScreen() should scroll player 1 out of a 4-column view")
+	}
+	if !strings.Contains(screen, ansiBold+"\033[7;31m0") {
+		t.Errorf("renderScreen() should highlight the current player: %q", screen)
+	}
+	if !strings.HasSuffix(screen, "player 0 at (0, 8)\r\nstatus") {
+		t.Errorf("renderScreen() status lines = %q", screen)
+	}
+	for _, c := range []struct{ pos, size, view, want int }{
+		{0, 10, 4, 0}, {8, 10, 4, 6}, {5, 10, 4, 3}, {2, 3, 4, 0},
+	} {
+		if got := viewportStart(c.pos, c.size, c.view); got != c.want {
+			t.Errorf("viewportStart(%d, %d, %d) = %d, expected %d", c.pos, c.size, c.view, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"labyrinth"
+)
+
+// runReplay 处理 labyrinth replay 子命令
+// 从当前地图按日志倒推出初始地图，再逐步回放
+func runReplay(args []string) int {
+	fs := flag.NewFlagSet("replay", flag.ContinueOnError)
+	mapFile := fs.String("map", "", "Map file (default: journal path without "+labyrinth.JournalSuffix+")")
+	speed := fs.Float64("speed", 1, "Steps per second (0 for no delay)")
+	// 允许 replay journal.log --speed 2 这种日志路径在前的写法
+	var journalFile string
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		journalFile, args = args[0], args[1:]
+	}
+	if err := fs.Parse(args); err != nil {
+		printUsage()
+		return 1
+	}
+	if journalFile == "" && fs.NArg() == 1 {
+		journalFile = fs.Arg(0)
+	} else if fs.NArg() > 0 {
+		printUsage()
+		return 1
+	}
+	if journalFile == "" || *speed < 0 {
+		printUsage()
+		return 1
+	}
+	if *mapFile == "" {
+		*mapFile = strings.TrimSuffix(journalFile, labyrinth.JournalSuffix)
+	}
+
+	entries, err := labyrinth.ReadJournal(journalFile)
+	if err != nil {
+		fmt.Println("Error reading journal:", err)
+		return 1
+	}
+	lab := &labyrinth.Labyrinth{}
+	if err := labyrinth.LoadMap(lab, *mapFile); err != nil {
+		fmt.Println("Error loading map:", err)
+		return 1
+	}
+	for i := len(entries) - 1; i >= 0; i-- {
+		if err := labyrinth.ApplyEntry(lab, entries[i], true); err != nil {
+			fmt.Println("Error rewinding journal:", err)
+			return 1
+		}
+	}
+
+	printMap(lab)
+	for i, entry := range entries {
+		if *speed > 0 {
+			time.Sleep(time.Duration(float64(time.Second) / *speed))
+		}
+		if err := labyrinth.ApplyEntry(lab, entry, false); err != nil {
+			fmt.Println("Error replaying journal:", err)
+			return 1
+		}
+		fmt.Printf("\nstep %d/%d: %s\n", i+1, len(entries), entry)
+		printMap(lab)
+	}
+	return 0
+}
+
+func printMap(lab *labyrinth.Labyrinth) {
+	writer := bufio.NewWriter(os.Stdout)
+	for i := 0; i < lab.Rows; i++ {
+		writer.WriteString(string(lab.Map[i]))
+		writer.WriteByte('\n')
+	}
+	writer.Flush()
+}
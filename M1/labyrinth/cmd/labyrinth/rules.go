@@ -0,0 +1,85 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"labyrinth"
+)
+
+// runRules 处理 labyrinth rules 子命令：设置规则并重置比赛状态
+// 重置前的日志存档，撤销与回放从重置后的地图开始
+func runRules(args []string) int {
+	fs := flag.NewFlagSet("rules", flag.ContinueOnError)
+	mapFile := fs.String("map", "", "Map file path")
+	mode := fs.String("mode", labyrinth.ModeFree, "Game mode (free/race/tag)")
+	turns := fs.Bool("turns", false, "Enforce turn order")
+	limit := fs.Int("limit", 0, "Move limit per player (0 for unlimited)")
+	if err := fs.Parse(args); err != nil || fs.NArg() > 0 || *mapFile == "" || *limit < 0 {
+		printUsage()
+		return 1
+	}
+	if *mode != labyrinth.ModeFree && *mode != labyrinth.ModeRace && *mode != labyrinth.ModeTag {
+		printUsage()
+		return 1
+	}
+	lock, err := labyrinth.LockMap(*mapFile)
+	if err != nil {
+		fmt.Println("Error locking map:", err)
+		return 1
+	}
+	defer lock.Unlock()
+	lab := &labyrinth.Labyrinth{}
+	if err := labyrinth.LoadMap(lab, *mapFile); err != nil {
+		fmt.Println("Error loading map:", err)
+		return 1
+	}
+	archive, err := labyrinth.ArchiveJournal(*mapFile, lab.Version)
+	if err != nil {
+		fmt.Println("Error archiving journal:", err)
+		return 1
+	}
+	lab.Game = labyrinth.NewGameState(labyrinth.Rules{Mode: *mode, TurnOrder: *turns, MoveLimit: *limit})
+	if err := labyrinth.CommitMap(lab, *mapFile); err != nil {
+		fmt.Println("Error saving map:", err)
+		// 地图没有重置，日志放回原处
+		if archive != "" {
+			if err := os.Rename(archive, labyrinth.JournalPath(*mapFile)); err != nil {
+				fmt.Println("Error restoring journal:", err)
+			}
+		}
+		return 1
+	}
+	return 0
+}
+
+// runStatus 处理 labyrinth status 子命令，只读取地图，不移动也不写文件
+// --format json 时输出地图与所有玩家的位置，前端用它刷新画面
+func runStatus(args []string) int {
+	fs := flag.NewFlagSet("status", flag.ContinueOnError)
+	mapFile := fs.String("map", "", "Map file path")
+	format := fs.String("format", "text", "Output format: text or json")
+	out := &reporter{json: jsonRequested(args)}
+	if out.json {
+		fs.SetOutput(io.Discard)
+	}
+	if err := fs.Parse(args); err != nil {
+		return out.badArgs(err)
+	}
+	if *format != "text" && *format != "json" {
+		return out.badArgs(fmt.Errorf("invalid --format %q", *format))
+	}
+	if fs.NArg() > 0 || *mapFile == "" {
+		return out.usage()
+	}
+	lab := &labyrinth.Labyrinth{}
+	if err := labyrinth.LoadMap(lab, *mapFile); err != nil {
+		return out.fail(exitLoadError, "Error loading map", err)
+	}
+	out.text("%s", labyrinth.FormatStatus(lab))
+	// 玩家 ID 都是数字字符，传 0 时不会排除任何玩家
+	out.state(labyrinth.NewGame(lab, *mapFile), 0)
+	return out.done()
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net"
+
+	"labyrinth"
+)
+
+// runServe 处理 labyrinth serve 子命令
+func runServe(args []string) int {
+	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+	mapFile := fs.String("map", "", "Map file path")
+	mapFileShort := fs.String("m", "", "Map file path (short)")
+	listen := fs.String("listen", ":4399", "Listen address")
+	if err := fs.Parse(args); err != nil || fs.NArg() > 0 {
+		printUsage()
+		return 1
+	}
+	if *mapFileShort != "" {
+		mapFile = mapFileShort
+	}
+	if *mapFile == "" {
+		printUsage()
+		return 1
+	}
+
+	server, err := labyrinth.NewServer(*mapFile)
+	if err != nil {
+		fmt.Println("Error loading map:", err)
+		return 1
+	}
+	ln, err := net.Listen("tcp", *listen)
+	if err != nil {
+		fmt.Println("Error listening:", err)
+		return 1
+	}
+	fmt.Printf("Labyrinth server listening on %s\n", ln.Addr())
+	if err := server.Serve(ln); err != nil {
+		fmt.Println("Error serving:", err)
+		return 1
+	}
+	return 0
+}
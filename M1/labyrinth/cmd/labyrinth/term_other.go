@@ -0,0 +1,29 @@
+//go:build !linux && !darwin
+
+package main
+
+import (
+	"errors"
+	"os"
+)
+
+// errUnsupportedTerminal 没有 termios ioctl 的平台上 play 不可用
+var errUnsupportedTerminal = errors.New("raw terminal mode is not supported on this platform")
+
+// resizeSignals 其他平台上没有窗口大小改变的信号
+var resizeSignals []os.Signal
+
+// terminalState 占位，其他平台上 makeRaw 总是失败
+type terminalState struct{}
+
+func makeRaw(fd int) (*terminalState, error) {
+	return nil, errUnsupportedTerminal
+}
+
+func restoreTerminal(fd int, state *terminalState) error {
+	return errUnsupportedTerminal
+}
+
+func terminalSize(fd int) (int, int, error) {
+	return 0, 0, errUnsupportedTerminal
+}
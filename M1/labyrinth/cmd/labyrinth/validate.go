@@ -0,0 +1,43 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+
+	"labyrinth"
+)
+
+// runValidate 处理 labyrinth validate 子命令，有问题时返回 1
+func runValidate(args []string) int {
+	fs := flag.NewFlagSet("validate", flag.ContinueOnError)
+	mapFile := fs.String("map", "", "Map file path")
+	mapFileShort := fs.String("m", "", "Map file path (short)")
+	asJSON := fs.Bool("json", false, "Print the report as JSON")
+	if err := fs.Parse(args); err != nil || fs.NArg() > 0 {
+		printUsage()
+		return 1
+	}
+	if *mapFileShort != "" {
+		mapFile = mapFileShort
+	}
+	if *mapFile == "" {
+		printUsage()
+		return 1
+	}
+	report, err := labyrinth.ValidateFile(*mapFile)
+	if err != nil {
+		fmt.Println("Error loading map:", err)
+		return 1
+	}
+	if *asJSON {
+		out, _ := json.MarshalIndent(report, "", "  ")
+		fmt.Println(string(out))
+	} else {
+		fmt.Print(labyrinth.FormatReport(report))
+	}
+	if !report.OK() {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,123 @@
+package labyrinth
+
+import (
+	"errors"
+	"fmt"
+)
+
+// 多层地图：
+//
+//	地图文件中单独一行 FloorSeparator 分隔楼层，每层行列数相同
+//	>   下楼梯，与下一层同一位置的 < 相连
+//	<   上楼梯，与上一层同一位置的 > 相连
+//
+// 内存中各层上下堆叠在同一个 Map 里，层与层之间插入一行墙，
+// 平面移动、寻路与连通性检查都不会直接跨层，只能经过楼梯
+const (
+	FloorSeparator = "---"
+	StairsUp       = '<'
+	StairsDown     = '>'
+)
+
+// 楼梯方向，与 up/down/left/right 一起传给 MovePlayer
+const (
+	DirectionUpstairs   = "upstairs"
+	DirectionDownstairs = "downstairs"
+)
+
+// IsStairs 是否为楼梯
+func IsStairs(ch byte) bool {
+	return ch == StairsUp || ch == StairsDown
+}
+
+// Floors 楼层数
+func Floors(labyrinth *Labyrinth) int {
+	if labyrinth.FloorRows == 0 {
+		return 1
+	}
+	return (labyrinth.Rows + 1) / (labyrinth.FloorRows + 1)
+}
+
+// FloorOf 某一行所在的楼层与层内行号
+func FloorOf(labyrinth *Labyrinth, row int) (int, int) {
+	if labyrinth.FloorRows == 0 {
+		return 0, row
+	}
+	return row / (labyrinth.FloorRows + 1), row % (labyrinth.FloorRows + 1)
+}
+
+// isFloorSeparator 是否为层与层之间插入的墙
+func isFloorSeparator(labyrinth *Labyrinth, row int) bool {
+	_, local := FloorOf(labyrinth, row)
+	return labyrinth.FloorRows > 0 && local == labyrinth.FloorRows
+}
+
+// StairLink 位置 p 上的楼梯 tile 通往的位置与方向；楼梯另一端缺失时返回 false
+func StairLink(labyrinth *Labyrinth, p Position, tile byte) (Position, string, bool) {
+	if labyrinth.FloorRows == 0 {
+		return p, "", false
+	}
+	target, want, direction := p, byte(StairsUp), DirectionDownstairs
+	switch tile {
+	case StairsDown:
+		target.Row += labyrinth.FloorRows + 1
+	case StairsUp:
+		target.Row -= labyrinth.FloorRows + 1
+		want, direction = StairsDown, DirectionUpstairs
+	default:
+		return p, "", false
+	}
+	if target.Row < 0 || target.Row >= labyrinth.Rows {
+		return p, "", false
+	}
+	other := labyrinth.Map[target.Row][target.Col]
+	if IsPlayerTile(other) {
+		other = TileUnder(labyrinth, other)
+	}
+	if other != want {
+		return p, "", false
+	}
+	return target, direction, true
+}
+
+// checkFloorHeight 检查刚读完的一层与第一层行数相同，floorRows 为 0 时记录第一层的行数
+func checkFloorHeight(height int, floorRows *int) error {
+	if height == 0 {
+		return errors.New("floor is empty")
+	}
+	if *floorRows == 0 {
+		*floorRows = height
+	} else if height != *floorRows {
+		return fmt.Errorf("floor has %d rows, expected %d", height, *floorRows)
+	}
+	return nil
+}
+
+// UnlinkedStairs 另一端没有对应楼梯的楼梯位置
+func UnlinkedStairs(labyrinth *Labyrinth) []Position {
+	var unlinked []Position
+	for i := 0; i < labyrinth.Rows; i++ {
+		for j := 0; j < labyrinth.Cols; j++ {
+			ch := labyrinth.Map[i][j]
+			if IsPlayerTile(ch) {
+				ch = TileUnder(labyrinth, ch)
+			}
+			if !IsStairs(ch) {
+				continue
+			}
+			if _, _, ok := StairLink(labyrinth, Position{i, j}, ch); !ok {
+				unlinked = append(unlinked, Position{i, j})
+			}
+		}
+	}
+	return unlinked
+}
+
+// stairTarget 玩家站在楼梯上时按 direction 上下楼的目标位置
+func stairTarget(labyrinth *Labyrinth, playerID byte, p Position, direction string) (Position, error) {
+	target, linked, ok := StairLink(labyrinth, p, TileUnder(labyrinth, playerID))
+	if !ok || linked != direction {
+		return p, fmt.Errorf("%w: cannot go %s here", ErrInvalidMove, direction)
+	}
+	return target, nil
+}
@@ -0,0 +1,122 @@
+package labyrinth
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// 两层地图：第 0 层右下角的 > 通往第 1 层同一位置的 <
+const twoFloors = "0.#\n#.>\n---\n.##\n..<\n"
+
+// TestLoadFloors 测试多层地图的加载与保存
+func TestLoadFloors(t *testing.T) {
+	mapFile := filepath.Join(t.TempDir(), "map.txt")
+	if err := os.WriteFile(mapFile, []byte(twoFloors), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	lab := &Labyrinth{}
+	if err := LoadMap(lab, mapFile); err != nil {
+		t.Fatalf("LoadMap() error: %v", err)
+	}
+	if lab.Rows != 5 || lab.FloorRows != 2 || Floors(lab) != 2 {
+		t.Errorf("LoadMap() rows=%d floorRows=%d floors=%d, expected 5, 2, 2", lab.Rows, lab.FloorRows, Floors(lab))
+	}
+	if string(lab.Map[2]) != "###" {
+		t.Errorf("separator row = %q, expected a wall", lab.Map[2])
+	}
+	if floor, row := FloorOf(lab, 4); floor != 1 || row != 1 {
+		t.Errorf("FloorOf(4) = %d, %d, expected 1, 1", floor, row)
+	}
+	if err := IsConnected(lab); err != nil {
+		t.Errorf("IsConnected() error: %v, floors are linked by stairs", err)
+	}
+	if err := SaveMap(lab, mapFile); err != nil {
+		t.Fatalf("SaveMap() error: %v", err)
+	}
+	if content, _ := os.ReadFile(mapFile); string(content) != twoFloors {
+		t.Errorf("SaveMap() content = %q, expected %q", content, twoFloors)
+	}
+
+	for _, bad := range []string{"0.\n---\n..\n..\n", "0.>\n---\n...\n", "---\n0.\n"} {
+		if err := os.WriteFile(mapFile, []byte(bad), 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+		if err := LoadMap(&Labyrinth{}, mapFile); err == nil {
+			t.Errorf("LoadMap(%q) should fail", bad)
+		}
+	}
+}
+
+// TestStairs 测试上下楼与跨层寻路
+func TestStairs(t *testing.T) {
+	mapFile := filepath.Join(t.TempDir(), "map.txt")
+	if err := os.WriteFile(mapFile, []byte(twoFloors), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	lab := &Labyrinth{}
+	if err := LoadMap(lab, mapFile); err != nil {
+		t.Fatalf("LoadMap() error: %v", err)
+	}
+	from := Position{0, 0}
+	path, err := ShortestPath(lab, from, Position{3, 0})
+	if err != nil {
+		t.Fatalf("ShortestPath() error: %v", err)
+	}
+	got := strings.Join(PathDirections(path), ",")
+	if got != "right,down,right,downstairs,left,left,up" {
+		t.Errorf("ShortestPath() = %s", got)
+	}
+	if astar, err := AStarPath(lab, from, Position{3, 0}); err != nil || len(astar) != len(path) {
+		t.Errorf("AStarPath() length %d, %v, expected %d", len(astar), err, len(path))
+	}
+
+	if err := MovePlayer(lab, '0', DirectionDownstairs); !errors.Is(err, ErrInvalidMove) {
+		t.Errorf("downstairs off the stairs error = %v, expected ErrInvalidMove", err)
+	}
+	for _, dir := range []string{"right", "down", "right"} {
+		if err := MovePlayer(lab, '0', dir); err != nil {
+			t.Fatalf("MovePlayer(%s) error: %v", dir, err)
+		}
+	}
+	if err := MovePlayer(lab, '0', "down"); err == nil {
+		t.Error("MovePlayer(down) should not walk through the floor")
+	}
+	if err := MovePlayer(lab, '0', DirectionUpstairs); !errors.Is(err, ErrInvalidMove) {
+		t.Errorf("upstairs on down stairs error = %v, expected ErrInvalidMove", err)
+	}
+	if err := MovePlayer(lab, '0', DirectionDownstairs); err != nil {
+		t.Fatalf("MovePlayer(downstairs) error: %v", err)
+	}
+	if p, _ := FindPlayer(lab, '0'); *p != (Position{4, 2}) || lab.Map[1][2] != '>' || TileUnder(lab, '0') != '<' {
+		t.Errorf("after downstairs player at %v, under %c, upper stairs %c", *p, TileUnder(lab, '0'), lab.Map[1][2])
+	}
+
+	// 站在楼梯上保存后重新加载，脚下的楼梯保留在元数据中
+	if err := SaveMap(lab, mapFile); err != nil {
+		t.Fatalf("SaveMap() error: %v", err)
+	}
+	if err := LoadMap(lab, mapFile); err != nil {
+		t.Fatalf("LoadMap() error: %v", err)
+	}
+	if err := MovePlayer(lab, '0', DirectionUpstairs); err != nil {
+		t.Errorf("MovePlayer(upstairs) error: %v", err)
+	}
+}
+
+// TestValidateFloors 测试多层地图的校验
+func TestValidateFloors(t *testing.T) {
+	if report := ValidateMap(strings.Split(strings.TrimSuffix(twoFloors, "\n"), "\n")); !report.OK() {
+		t.Errorf("ValidateMap() problems: %+v", report.Problems)
+	}
+	report := ValidateMap([]string{"0.>", "---", "...", "...", "---", "..."})
+	kinds := make(map[string]int)
+	for _, p := range report.Problems {
+		kinds[p.Kind]++
+	}
+	if kinds[ProblemFloors] != 1 || kinds[ProblemStairs] != 1 {
+		t.Errorf("ValidateMap() problems = %+v, expected one floors and one stairs problem", report.Problems)
+	}
+}
@@ -0,0 +1,170 @@
+package labyrinth
+
+import (
+	"errors"
+	"os"
+	"strings"
+)
+
+// FogSuffix 玩家记忆文件：map.txt -> map.txt.fog0
+const FogSuffix = ".fog"
+
+// DefaultViewRadius 默认视野半径
+const DefaultViewRadius = 5
+
+const (
+	ansiDim   = "\033[2m"
+	ansiReset = "\033[0m"
+)
+
+// FogPath 玩家记忆文件路径
+func FogPath(mapFile string, playerID byte) string {
+	return mapFile + FogSuffix + string(playerID)
+}
+
+// LineOfSight 检查两点之间是否没有墙壁遮挡（Bresenham 直线，不含两端）
+func LineOfSight(labyrinth *Labyrinth, from, to Position) bool {
+	dr, dc := abs(to.Row-from.Row), abs(to.Col-from.Col)
+	sr, sc := 1, 1
+	if to.Row < from.Row {
+		sr = -1
+	}
+	if to.Col < from.Col {
+		sc = -1
+	}
+	err := dc - dr
+	row, col := from.Row, from.Col
+	for row != to.Row || col != to.Col {
+		if (row != from.Row || col != from.Col) && labyrinth.Map[row][col] == '#' {
+			return false
+		}
+		e2 := 2 * err
+		if e2 > -dr {
+			err -= dr
+			col += sc
+		}
+		if e2 < dc {
+			err += dc
+			row += sr
+		}
+	}
+	return true
+}
+
+// View 一次观察的可见格子，只保存以观察点为中心、边长 2*radius+1 的窗口
+type View struct {
+	Top, Left  int // 窗口左上角在地图中的位置
+	Rows, Cols int
+	cells      Bitset
+}
+
+// Visible 格子是否可见，窗口之外的格子都不可见
+func (v *View) Visible(row, col int) bool {
+	row, col = row-v.Top, col-v.Left
+	if row < 0 || row >= v.Rows || col < 0 || col >= v.Cols {
+		return false
+	}
+	return v.cells.Get(row*v.Cols + col)
+}
+
+// VisibleCells 计算从 from 出发在 radius 范围内可见的格子
+// 墙壁本身可见，但会挡住其后的格子；内存与视野半径有关，与地图大小无关
+func VisibleCells(labyrinth *Labyrinth, from Position, radius int) *View {
+	top, left := max(0, from.Row-radius), max(0, from.Col-radius)
+	bottom, right := min(labyrinth.Rows-1, from.Row+radius), min(labyrinth.Cols-1, from.Col+radius)
+	view := &View{Top: top, Left: left, Rows: max(0, bottom-top+1), Cols: max(0, right-left+1)}
+	view.cells = NewBitset(view.Rows * view.Cols)
+	for i := top; i <= bottom; i++ {
+		for j := left; j <= right; j++ {
+			dr, dc := i-from.Row, j-from.Col
+			if dr*dr+dc*dc <= radius*radius && LineOfSight(labyrinth, from, Position{i, j}) {
+				view.cells.Set((i-top)*view.Cols + j - left)
+			}
+		}
+	}
+	return view
+}
+
+// UpdateMemory 把当前可见的格子写入记忆，记忆中 ' ' 表示从未见过
+func UpdateMemory(labyrinth *Labyrinth, view *View, memory [][]byte) [][]byte {
+	if len(memory) != labyrinth.Rows {
+		memory = nil
+	}
+	if memory == nil {
+		memory = make([][]byte, labyrinth.Rows)
+	}
+	for i := 0; i < labyrinth.Rows; i++ {
+		if len(memory[i]) != labyrinth.Cols {
+			memory[i] = []byte(strings.Repeat(" ", labyrinth.Cols))
+		}
+	}
+	// 只有窗口内的格子可能可见
+	for i := view.Top; i < view.Top+view.Rows; i++ {
+		for j := view.Left; j < view.Left+view.Cols; j++ {
+			if view.Visible(i, j) {
+				memory[i][j] = labyrinth.Map[i][j]
+			}
+		}
+	}
+	return memory
+}
+
+// RenderView 可见格子原样显示，记得但看不见的格子显示上次看到的内容并变暗
+func RenderView(labyrinth *Labyrinth, view *View, memory [][]byte) string {
+	var sb strings.Builder
+	for i := 0; i < labyrinth.Rows; i++ {
+		dim := false
+		for j := 0; j < labyrinth.Cols; j++ {
+			var ch byte
+			remembered := false
+			switch {
+			case view.Visible(i, j):
+				ch = labyrinth.Map[i][j]
+			case memory != nil && memory[i][j] != ' ':
+				ch = memory[i][j]
+				remembered = true
+			default:
+				ch = ' '
+			}
+			if remembered != dim {
+				if remembered {
+					sb.WriteString(ansiDim)
+				} else {
+					sb.WriteString(ansiReset)
+				}
+				dim = remembered
+			}
+			sb.WriteByte(ch)
+		}
+		if dim {
+			sb.WriteString(ansiReset)
+		}
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
+
+// LoadMemory 读取玩家记忆，文件不存在时返回 nil
+func LoadMemory(filename string) ([][]byte, error) {
+	lines, err := readFile(filename)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	memory := make([][]byte, len(lines))
+	for i, line := range lines {
+		memory[i] = []byte(line)
+	}
+	return memory, nil
+}
+
+// SaveMemory 保存玩家记忆
+func SaveMemory(filename string, memory [][]byte) error {
+	lines := make([]string, len(memory))
+	for i, row := range memory {
+		lines[i] = string(row)
+	}
+	return writeFile(filename, lines)
+}
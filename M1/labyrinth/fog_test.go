@@ -0,0 +1,70 @@
+package labyrinth
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestLineOfSight 测试墙壁遮挡视线
+func TestLineOfSight(t *testing.T) {
+	lab := newTestLabyrinth(
+		"0.#..",
+		".....",
+	)
+	if !LineOfSight(lab, Position{0, 0}, Position{0, 2}) {
+		t.Error("wall itself should be visible")
+	}
+	if LineOfSight(lab, Position{0, 0}, Position{0, 4}) {
+		t.Error("cells behind a wall should not be visible")
+	}
+	if !LineOfSight(lab, Position{1, 0}, Position{1, 4}) {
+		t.Error("open row should be visible")
+	}
+}
+
+// TestVisibleCells 测试视野半径
+func TestVisibleCells(t *testing.T) {
+	lab := newTestLabyrinth(
+		"0......",
+		".......",
+	)
+	visible := VisibleCells(lab, Position{0, 0}, 2)
+	if !visible.Visible(0, 2) || !visible.Visible(1, 1) {
+		t.Error("cells within radius should be visible")
+	}
+	if visible.Visible(0, 3) || visible.Visible(1, 2) {
+		t.Error("cells outside radius should not be visible")
+	}
+
+	// 只保存视野半径内的窗口，窗口在地图边缘截断
+	visible = VisibleCells(lab, Position{1, 4}, 1)
+	if visible.Top != 0 || visible.Left != 3 || visible.Rows != 2 || visible.Cols != 3 {
+		t.Errorf("VisibleCells() window = %+v, expected 2x3 at (0, 3)", *visible)
+	}
+	if !visible.Visible(0, 4) || !visible.Visible(1, 5) || visible.Visible(0, 3) || visible.Visible(1, 6) {
+		t.Error("window cells should follow the view radius")
+	}
+}
+
+// TestRenderView 测试记忆中的格子变暗，未见过的格子隐藏
+func TestRenderView(t *testing.T) {
+	lab := newTestLabyrinth("0.#..")
+	visible := VisibleCells(lab, Position{0, 0}, 10)
+	memory := UpdateMemory(lab, visible, nil)
+	if string(memory[0]) != "0.#  " {
+		t.Errorf("UpdateMemory() = %q", string(memory[0]))
+	}
+	if got := RenderView(lab, visible, memory); got != "0.#  \n" {
+		t.Errorf("RenderView() = %q", got)
+	}
+
+	// 玩家离开后，之前看到的格子只出现在记忆中
+	if err := MovePlayer(lab, '0', "right"); err != nil {
+		t.Fatalf("MovePlayer() error: %v", err)
+	}
+	visible = VisibleCells(lab, Position{0, 1}, 0)
+	got := RenderView(lab, visible, memory)
+	if !strings.Contains(got, ansiDim+"0"+ansiReset+"0") {
+		t.Errorf("RenderView() = %q, expected dimmed remembered cell", got)
+	}
+}
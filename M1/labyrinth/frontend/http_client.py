@@ -0,0 +1,84 @@
+#!/usr/bin/env python3
+# Online client for `labyrinth http`, replaces the SSH flow of online.py
+
+import argparse
+import curses
+import json
+import threading
+import urllib.error
+import urllib.request
+
+KEYS = {
+    ord("w"): "up", curses.KEY_UP: "up",
+    ord("a"): "left", curses.KEY_LEFT: "left",
+    ord("s"): "down", curses.KEY_DOWN: "down",
+    ord("d"): "right", curses.KEY_RIGHT: "right",
+    ord("<"): "upstairs",
+    ord(">"): "downstairs",
+}
+
+
+def request(url, body=None):
+    data = json.dumps(body).encode() if body is not None else None
+    req = urllib.request.Request(url, data=data, headers={"Content-Type": "application/json"})
+    try:
+        with urllib.request.urlopen(req) as resp:
+            return json.load(resp)
+    except urllib.error.HTTPError as e:
+        return json.load(e)
+
+
+def watch_events(server, changed):
+    """Wake up the main loop whenever any player moves."""
+    try:
+        with urllib.request.urlopen(f"{server}/events") as resp:
+            for line in resp:
+                if line.startswith(b"data: "):
+                    changed.set()
+    except OSError:
+        pass
+
+
+def draw(stdscr, state, player_id, status):
+    stdscr.erase()
+    for i, line in enumerate(state.get("map", [])):
+        stdscr.addstr(i, 0, line)
+    row = len(state.get("map", [])) + 1
+    stdscr.addstr(row, 0, f"Player {player_id}: WASD/arrows to move, < > for stairs, Q to quit")
+    stdscr.addstr(row + 1, 0, status)
+    stdscr.refresh()
+
+
+def run(stdscr, server, player_id):
+    curses.curs_set(0)
+    stdscr.timeout(200)
+    changed = threading.Event()
+    threading.Thread(target=watch_events, args=(server, changed), daemon=True).start()
+
+    status = ""
+    state = request(f"{server}/map")
+    while True:
+        draw(stdscr, state, player_id, status)
+        key = stdscr.getch()
+        if key == ord("q"):
+            break
+        if key in KEYS:
+            result = request(f"{server}/players/{player_id}/move", {"direction": KEYS[key]})
+            error = result.get("error")
+            status = f"{error['code']}: {error['message']}" if error else f"moved {KEYS[key]}"
+            changed.set()
+        if changed.is_set():
+            changed.clear()
+            state = request(f"{server}/map")
+
+
+def main():
+    parser = argparse.ArgumentParser(description="Online labyrinth client for `labyrinth http`")
+    parser.add_argument("--server", default="http://localhost:8081", help="HTTP API address")
+    parser.add_argument("--player", "-p", required=True, help="Player ID (0-9)")
+    args = parser.parse_args()
+    curses.wrapper(run, args.server.rstrip("/"), args.player)
+
+
+if __name__ == "__main__":
+    main()
@@ -3,6 +3,7 @@
 
 
 import argparse
+import json
 import os
 import subprocess
 import sys
@@ -101,17 +102,30 @@ def create_temp_map_file(original_map_file):
     return temp_path
 
 
+def parse_result(result):
+    """Parse the JSON printed by labyrinth --format json."""
+    try:
+        return json.loads(result.stdout)
+    except ValueError:
+        return {}
+
+
+def error_message(result):
+    error = parse_result(result).get("error") or {}
+    return f"{error.get('code', result.returncode)}: {error.get('message', result.stdout.strip())}"
+
+
 def get_map_state(labyrinth_path, map_file):
-    result = subprocess.run([str(labyrinth_path), "--map", map_file, "--player", "0"], capture_output=True, text=True)
+    result = subprocess.run([str(labyrinth_path), "status", "--map", map_file, "--format", "json"], capture_output=True, text=True)
     if result.returncode != 0:
-        print(f"Error getting map state: {result.stderr}")
-    return result.stdout.strip().split("\n")
+        print(f"Error getting map state: {error_message(result)}")
+    return parse_result(result).get("map", [])
 
 
 def move_player(labyrinth_path, map_file, player_id, direction):
-    result = subprocess.run([str(labyrinth_path), "--map", map_file, "--player", str(player_id), "--move", direction], capture_output=True, text=True)
+    result = subprocess.run([str(labyrinth_path), "--map", map_file, "--player", str(player_id), "--move", direction, "--format", "json"], capture_output=True, text=True)
     if result.returncode != 0:
-        print(f"Error moving player {player_id}: {result.stderr}")
+        print(f"Error moving player {player_id}: {error_message(result)}")
     return result.returncode == 0
 
 
@@ -0,0 +1,268 @@
+package labyrinth
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MoveEvent 一次成功的移动，Move 之后同步通知所有订阅者
+type MoveEvent struct {
+	Player    byte
+	Direction string
+	From      Position
+	To        Position
+	Finished  bool        // 这一步到达了出口
+	Effects   MoveEffects // 位置之外的修改，写入日志供撤销使用
+}
+
+// Player 玩家的公开状态
+type Player struct {
+	ID       byte
+	Position Position
+	Keys     string
+	Finished bool
+	Captured bool
+}
+
+// Game 可嵌入的游戏引擎，封装 加载-移动-保存 流程
+// 多个进程共享同一张地图时，调用者需要在 Load 到 Save 之间持有 LockMap
+type Game struct {
+	mu        sync.Mutex
+	labyrinth *Labyrinth
+	file      string
+	pending   []JournalEntry // 还没有写入日志的移动，Save 时一并写入
+
+	nextID   int
+	handlers map[int]func(MoveEvent)
+}
+
+// NewGame 用已有的地图创建游戏，file 为空时 Save 不可用
+func NewGame(labyrinth *Labyrinth, file string) *Game {
+	return &Game{labyrinth: labyrinth, file: file}
+}
+
+// Load 加载地图文件并检查连通性，不连通时返回 ErrNotConnected
+func (g *Game) Load(filename string) error {
+	labyrinth := &Labyrinth{}
+	if err := LoadMap(labyrinth, filename); err != nil {
+		return err
+	}
+	if err := IsConnected(labyrinth); err != nil {
+		return err
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.labyrinth, g.file, g.pending = labyrinth, filename, nil
+	return nil
+}
+
+// Labyrinth 底层地图，直接修改时不会产生事件
+func (g *Game) Labyrinth() *Labyrinth {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.labyrinth
+}
+
+// Move 在规则约束下移动玩家，非法移动返回包装了 ErrInvalidMove 的错误
+func (g *Game) Move(playerID byte, direction string) (MoveEvent, error) {
+	g.mu.Lock()
+	if g.labyrinth == nil {
+		g.mu.Unlock()
+		return MoveEvent{}, errors.New("no map loaded")
+	}
+	event, err := playStep(g.labyrinth, playerID, direction)
+	if err != nil {
+		g.mu.Unlock()
+		return MoveEvent{}, err
+	}
+	g.record(event)
+	handlers := g.subscribers()
+	g.mu.Unlock()
+
+	// 不持有锁通知，订阅者可以在回调中调用 Players、Render 等方法
+	for _, fn := range handlers {
+		fn(event)
+	}
+	return event, nil
+}
+
+// MoveAll 原子地执行批量移动（见 PlayMoves），全部成功后才按顺序通知订阅者
+func (g *Game) MoveAll(playerID byte, moves []string) ([]MoveEvent, error) {
+	g.mu.Lock()
+	if g.labyrinth == nil {
+		g.mu.Unlock()
+		return nil, errors.New("no map loaded")
+	}
+	events, err := PlayMoves(g.labyrinth, playerID, moves)
+	if err != nil {
+		g.mu.Unlock()
+		return nil, err
+	}
+	for _, event := range events {
+		g.record(event)
+	}
+	handlers := g.subscribers()
+	g.mu.Unlock()
+
+	for _, event := range events {
+		for _, fn := range handlers {
+			fn(event)
+		}
+	}
+	return events, nil
+}
+
+// record 把移动加入待写日志，调用方持有 g.mu
+func (g *Game) record(event MoveEvent) {
+	g.pending = append(g.pending, journalEntry(event))
+}
+
+// journalEntry 移动事件对应的日志记录
+func journalEntry(event MoveEvent) JournalEntry {
+	return JournalEntry{
+		Time:      time.Now(),
+		Action:    ActionMove,
+		Player:    event.Player,
+		Direction: event.Direction,
+		From:      event.From,
+		To:        event.To,
+		Effects:   event.Effects,
+	}
+}
+
+// subscribers 按订阅顺序返回回调，调用方持有 g.mu
+func (g *Game) subscribers() []func(MoveEvent) {
+	handlers := make([]func(MoveEvent), 0, len(g.handlers))
+	for id := 0; id < g.nextID; id++ {
+		if fn, ok := g.handlers[id]; ok {
+			handlers = append(handlers, fn)
+		}
+	}
+	return handlers
+}
+
+// Undo 撤销日志中最近一次移动
+func (g *Game) Undo() (*JournalEntry, error) {
+	return g.history(UndoMove)
+}
+
+// Redo 重做最近一次撤销
+func (g *Game) Redo() (*JournalEntry, error) {
+	return g.history(RedoMove)
+}
+
+func (g *Game) history(apply func(*Labyrinth, string) (*JournalEntry, error)) (*JournalEntry, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.labyrinth == nil || g.file == "" {
+		return nil, errors.New("no map file loaded")
+	}
+	if len(g.pending) > 0 {
+		return nil, errors.New("save pending moves first")
+	}
+	entry, err := apply(g.labyrinth, JournalPath(g.file))
+	if err != nil {
+		return nil, err
+	}
+	g.pending = append(g.pending, *entry)
+	return entry, nil
+}
+
+// Players 地图上的玩家（含已到达出口的玩家与被抓的玩家），按 ID 排序
+func (g *Game) Players() []Player {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.labyrinth == nil {
+		return nil
+	}
+	positions := PlayerPositions(g.labyrinth)
+	var players []Player
+	for id := byte('0'); id <= '9'; id++ {
+		player := Player{
+			ID:       id,
+			Position: Position{-1, -1},
+			Keys:     g.labyrinth.Inventory[id],
+			Finished: g.labyrinth.Finished[id],
+		}
+		if g.labyrinth.Game != nil {
+			player.Captured = g.labyrinth.Game.Captured[id]
+		}
+		if p, ok := positions[id]; ok {
+			player.Position = p
+		} else if !player.Captured {
+			continue
+		}
+		players = append(players, player)
+	}
+	return players
+}
+
+// Render 地图的文本形式，每行一个迷宫行，多层地图的层与层之间是一行墙
+func (g *Game) Render() string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.labyrinth == nil {
+		return ""
+	}
+	var sb strings.Builder
+	sb.Grow(g.labyrinth.Rows * (g.labyrinth.Cols + 1))
+	for i := 0; i < g.labyrinth.Rows; i++ {
+		sb.Write(g.labyrinth.Map[i])
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
+
+// Save 提交到加载时的地图文件并写入日志；文件被其他进程改过时返回 ErrStaleMap
+// 日志写入失败时撤回还没写入日志的移动并重新提交地图，地图与日志保持一致
+func (g *Game) Save() error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.labyrinth == nil || g.file == "" {
+		return errors.New("no map file loaded")
+	}
+	if err := CommitMap(g.labyrinth, g.file); err != nil {
+		return err
+	}
+	for len(g.pending) > 0 {
+		if err := AppendJournal(JournalPath(g.file), g.pending[0]); err != nil {
+			return g.rollback(fmt.Errorf("write journal: %w", err))
+		}
+		g.pending = g.pending[1:]
+	}
+	return nil
+}
+
+// rollback 倒序撤回 pending 中的移动，再以更新的版本号提交地图，调用方持有 g.mu
+func (g *Game) rollback(err error) error {
+	for i := len(g.pending) - 1; i >= 0; i-- {
+		if undoErr := ApplyEntry(g.labyrinth, g.pending[i], true); undoErr != nil {
+			return fmt.Errorf("%w, undo move: %v", err, undoErr)
+		}
+	}
+	g.pending = nil
+	if commitErr := CommitMap(g.labyrinth, g.file); commitErr != nil {
+		return fmt.Errorf("%w, restore map: %v", err, commitErr)
+	}
+	return err
+}
+
+// Subscribe 注册移动事件回调，返回取消订阅的函数
+func (g *Game) Subscribe(fn func(MoveEvent)) func() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.handlers == nil {
+		g.handlers = make(map[int]func(MoveEvent))
+	}
+	id := g.nextID
+	g.nextID++
+	g.handlers[id] = fn
+	return func() {
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		delete(g.handlers, id)
+	}
+}
@@ -0,0 +1,129 @@
+package labyrinth
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestGame 测试 Game 的加载、移动、事件与保存
+func TestGame(t *testing.T) {
+	mapFile := filepath.Join(t.TempDir(), "map.txt")
+	if err := os.WriteFile(mapFile, []byte("0..\n#.#\n1.E\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	game := &Game{}
+	if err := game.Load(mapFile); err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+
+	var events []MoveEvent
+	unsubscribe := game.Subscribe(func(e MoveEvent) { events = append(events, e) })
+	if _, err := game.Move('0', "up"); !errors.Is(err, ErrInvalidMove) {
+		t.Errorf("Move(up) error = %v, expected ErrInvalidMove", err)
+	}
+	if _, err := game.Move('0', "sideways"); !errors.Is(err, ErrInvalidMove) {
+		t.Errorf("Move(sideways) error = %v, expected ErrInvalidMove", err)
+	}
+	event, err := game.Move('0', "right")
+	if err != nil {
+		t.Fatalf("Move(right) error: %v", err)
+	}
+	if len(events) != 1 || events[0] != event || event.From != (Position{0, 0}) || event.To != (Position{0, 1}) {
+		t.Errorf("events = %+v, expected one move from (0, 0) to (0, 1)", events)
+	}
+	unsubscribe()
+	if _, err := game.Move('1', "right"); err != nil {
+		t.Fatalf("Move(right) error: %v", err)
+	}
+	if len(events) != 1 {
+		t.Errorf("got %d events after unsubscribe, expected 1", len(events))
+	}
+
+	players := game.Players()
+	if len(players) != 2 || players[0].ID != '0' || players[1].Position != (Position{2, 1}) {
+		t.Errorf("Players() = %+v", players)
+	}
+	if got := game.Render(); got != ".0.\n#.#\n.1E\n" {
+		t.Errorf("Render() = %q", got)
+	}
+
+	if err := game.Save(); err != nil {
+		t.Fatalf("Save() error: %v", err)
+	}
+	entries, err := ReadJournal(JournalPath(mapFile))
+	if err != nil || len(entries) != 2 {
+		t.Errorf("ReadJournal() = %d entries, %v, expected 2", len(entries), err)
+	}
+	reloaded := &Game{}
+	if err := reloaded.Load(mapFile); err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	if reloaded.Render() != game.Render() {
+		t.Errorf("saved map = %q, expected %q", reloaded.Render(), game.Render())
+	}
+}
+
+// TestGameErrors 测试可判断的错误类型
+func TestGameErrors(t *testing.T) {
+	mapFile := filepath.Join(t.TempDir(), "map.txt")
+	if err := os.WriteFile(mapFile, []byte("0.#.\n..#.\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	if err := (&Game{}).Load(mapFile); !errors.Is(err, ErrNotConnected) {
+		t.Errorf("Load() error = %v, expected ErrNotConnected", err)
+	}
+	if _, err := Generate(GenerateOptions{Rows: MaxRows + 1, Cols: 5, Algorithm: AlgoBacktracker}); !errors.Is(err, ErrMapTooLarge) {
+		t.Errorf("Generate() error = %v, expected ErrMapTooLarge", err)
+	}
+	if err := NewGame(newTestLabyrinth("0."), "").Save(); err == nil {
+		t.Error("Save() without a map file should fail")
+	}
+}
+
+// TestGameSaveJournalError 测试日志写不进去时 Save 撤回已提交的地图
+func TestGameSaveJournalError(t *testing.T) {
+	mapFile := filepath.Join(t.TempDir(), "map.txt")
+	if err := os.WriteFile(mapFile, []byte("0..\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Join(JournalPath(mapFile), "sub"), 0755); err != nil {
+		t.Fatalf("MkdirAll error: %v", err)
+	}
+	game := &Game{}
+	if err := game.Load(mapFile); err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	if _, err := game.MoveAll('0', []string{"right", "right"}); err != nil {
+		t.Fatalf("MoveAll() error: %v", err)
+	}
+	if err := game.Save(); err == nil {
+		t.Fatal("Save() with unwritable journal should fail")
+	}
+	if got := game.Render(); got != "0..\n" {
+		t.Errorf("map after failed Save = %q, expected the moves undone", got)
+	}
+	saved := &Labyrinth{}
+	if err := LoadMap(saved, mapFile); err != nil {
+		t.Fatalf("LoadMap() error: %v", err)
+	}
+	if string(saved.Map[0]) != "0.." || saved.Version != 2 {
+		t.Errorf("saved map = %q, version %d, expected 0.. version 2", string(saved.Map[0]), saved.Version)
+	}
+
+	// 日志恢复后 Save 正常提交
+	if err := os.RemoveAll(JournalPath(mapFile)); err != nil {
+		t.Fatalf("RemoveAll error: %v", err)
+	}
+	if _, err := game.Move('0', "right"); err != nil {
+		t.Fatalf("Move(right) error: %v", err)
+	}
+	if err := game.Save(); err != nil {
+		t.Fatalf("Save() error: %v", err)
+	}
+	entries, err := ReadJournal(JournalPath(mapFile))
+	if err != nil || len(entries) != 1 {
+		t.Errorf("journal = %v, %v, expected one entry", entries, err)
+	}
+}
@@ -0,0 +1,271 @@
+package labyrinth
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+)
+
+// 迷宫生成算法
+const (
+	AlgoBacktracker = "backtracker"
+	AlgoPrim        = "prim"
+	AlgoCave        = "cave"
+)
+
+// GenerateOptions 生成参数
+type GenerateOptions struct {
+	Rows      int
+	Cols      int
+	Seed      uint64
+	Density   float64 // 墙壁占比目标，迷宫算法只会拆墙，不会加墙
+	Players   int
+	Algorithm string
+}
+
+// Generate 生成一张连通的地图，相同参数与种子总是得到相同结果
+func Generate(opts GenerateOptions) (*Labyrinth, error) {
+	if opts.Rows < 3 || opts.Cols < 3 {
+		return nil, errors.New("map is too small")
+	}
+	if opts.Rows > MaxRows || opts.Cols > MaxCols {
+		return nil, ErrMapTooLarge
+	}
+	if opts.Players < 0 || opts.Players > 10 {
+		return nil, errors.New("players must be between 0 and 10")
+	}
+	if opts.Density < 0 || opts.Density > 1 {
+		return nil, errors.New("density must be between 0 and 1")
+	}
+	rng := rand.New(rand.NewPCG(opts.Seed, opts.Seed))
+	labyrinth := newWallLabyrinth(opts.Rows, opts.Cols)
+
+	switch opts.Algorithm {
+	case AlgoBacktracker, "":
+		carveBacktracker(labyrinth, rng)
+		openLoops(labyrinth, rng, opts.Density)
+	case AlgoPrim:
+		carvePrim(labyrinth, rng)
+		openLoops(labyrinth, rng, opts.Density)
+	case AlgoCave:
+		growCave(labyrinth, rng, opts.Density)
+	default:
+		return nil, fmt.Errorf("unknown algorithm %q", opts.Algorithm)
+	}
+
+	if err := placePlayers(labyrinth, rng, opts.Players); err != nil {
+		return nil, err
+	}
+	if err := IsConnected(labyrinth); err != nil {
+		return nil, err
+	}
+	return labyrinth, nil
+}
+
+func newWallLabyrinth(rows, cols int) *Labyrinth {
+	labyrinth := &Labyrinth{Map: make([][]byte, rows), Rows: rows, Cols: cols}
+	for i := range labyrinth.Map {
+		labyrinth.Map[i] = make([]byte, cols)
+		for j := range labyrinth.Map[i] {
+			labyrinth.Map[i][j] = '#'
+		}
+	}
+	return labyrinth
+}
+
+// isMazeCell 迷宫算法中的格子位于奇数行列，格子之间隔一堵墙
+func isMazeCell(labyrinth *Labyrinth, row, col int) bool {
+	return row > 0 && col > 0 && row < labyrinth.Rows-1 && col < labyrinth.Cols-1 && row%2 == 1 && col%2 == 1
+}
+
+// carveBacktracker 递归回溯（用显式栈实现）
+func carveBacktracker(labyrinth *Labyrinth, rng *rand.Rand) {
+	start := Position{1, 1}
+	labyrinth.Map[start.Row][start.Col] = '.'
+	stack := []Position{start}
+	for len(stack) > 0 {
+		cur := stack[len(stack)-1]
+		var next []Position
+		for _, d := range Directions {
+			p := Position{cur.Row + 2*d.DRow, cur.Col + 2*d.DCol}
+			if isMazeCell(labyrinth, p.Row, p.Col) && labyrinth.Map[p.Row][p.Col] == '#' {
+				next = append(next, p)
+			}
+		}
+		if len(next) == 0 {
+			stack = stack[:len(stack)-1]
+			continue
+		}
+		p := next[rng.IntN(len(next))]
+		labyrinth.Map[(cur.Row+p.Row)/2][(cur.Col+p.Col)/2] = '.'
+		labyrinth.Map[p.Row][p.Col] = '.'
+		stack = append(stack, p)
+	}
+}
+
+// carvePrim 随机化 Prim 算法：每次从边界墙中随机挑一堵打通
+func carvePrim(labyrinth *Labyrinth, rng *rand.Rand) {
+	type wall struct{ from, to Position }
+	var frontier []wall
+	add := func(cur Position) {
+		labyrinth.Map[cur.Row][cur.Col] = '.'
+		for _, d := range Directions {
+			p := Position{cur.Row + 2*d.DRow, cur.Col + 2*d.DCol}
+			if isMazeCell(labyrinth, p.Row, p.Col) && labyrinth.Map[p.Row][p.Col] == '#' {
+				frontier = append(frontier, wall{cur, p})
+			}
+		}
+	}
+	add(Position{1, 1})
+	for len(frontier) > 0 {
+		i := rng.IntN(len(frontier))
+		w := frontier[i]
+		frontier[i] = frontier[len(frontier)-1]
+		frontier = frontier[:len(frontier)-1]
+		if labyrinth.Map[w.to.Row][w.to.Col] != '#' {
+			continue
+		}
+		labyrinth.Map[(w.from.Row+w.to.Row)/2][(w.from.Col+w.to.Col)/2] = '.'
+		add(w.to)
+	}
+}
+
+// openLoops 随机拆掉连接两块空地的内墙，直到墙壁占比不超过 density
+// 只拆墙不加墙，因此不会破坏连通性
+func openLoops(labyrinth *Labyrinth, rng *rand.Rand, density float64) {
+	var candidates []Position
+	walls := 0
+	for i := 0; i < labyrinth.Rows; i++ {
+		for j := 0; j < labyrinth.Cols; j++ {
+			if labyrinth.Map[i][j] != '#' {
+				continue
+			}
+			walls++
+			if i == 0 || j == 0 || i == labyrinth.Rows-1 || j == labyrinth.Cols-1 {
+				continue
+			}
+			if IsEmptySpace(labyrinth, i-1, j) || IsEmptySpace(labyrinth, i+1, j) ||
+				IsEmptySpace(labyrinth, i, j-1) || IsEmptySpace(labyrinth, i, j+1) {
+				candidates = append(candidates, Position{i, j})
+			}
+		}
+	}
+	rng.Shuffle(len(candidates), func(i, j int) {
+		candidates[i], candidates[j] = candidates[j], candidates[i]
+	})
+	total := float64(labyrinth.Rows * labyrinth.Cols)
+	for _, p := range candidates {
+		if float64(walls)/total <= density {
+			break
+		}
+		labyrinth.Map[p.Row][p.Col] = '.'
+		walls--
+	}
+}
+
+// growCave 元胞自动机生成洞穴，只保留最大的连通区域
+func growCave(labyrinth *Labyrinth, rng *rand.Rand, density float64) {
+	for i := 1; i < labyrinth.Rows-1; i++ {
+		for j := 1; j < labyrinth.Cols-1; j++ {
+			if rng.Float64() >= density {
+				labyrinth.Map[i][j] = '.'
+			}
+		}
+	}
+	// 两张网格轮流作为上一步与下一步，边界始终是墙，内部每一步全部重写
+	next := newWallLabyrinth(labyrinth.Rows, labyrinth.Cols).Map
+	for step := 0; step < 4; step++ {
+		for i := 1; i < labyrinth.Rows-1; i++ {
+			for j := 1; j < labyrinth.Cols-1; j++ {
+				walls := 0
+				for di := -1; di <= 1; di++ {
+					for dj := -1; dj <= 1; dj++ {
+						if (di != 0 || dj != 0) && labyrinth.Map[i+di][j+dj] == '#' {
+							walls++
+						}
+					}
+				}
+				if walls < 5 {
+					next[i][j] = '.'
+				} else {
+					next[i][j] = '#'
+				}
+			}
+		}
+		labyrinth.Map, next = next, labyrinth.Map
+	}
+	keepLargestRegion(labyrinth)
+}
+
+// keepLargestRegion 把除最大连通区域以外的空地都填成墙
+// 第一遍用位图找出最大区域的起点，第二遍从起点重新标记，内存占用为两张位图加上 BFS 队列
+func keepLargestRegion(labyrinth *Labyrinth) {
+	visited := NewBitset(labyrinth.Rows * labyrinth.Cols)
+	var queue []int32
+	var best Position
+	bestSize := 0
+	for i := 0; i < labyrinth.Rows; i++ {
+		for j := 0; j < labyrinth.Cols; j++ {
+			if labyrinth.Map[i][j] != '.' || visited.Get(i*labyrinth.Cols+j) {
+				continue
+			}
+			if size := floodRegion(labyrinth, Position{i, j}, visited, &queue); size > bestSize {
+				best, bestSize = Position{i, j}, size
+			}
+		}
+	}
+	if bestSize == 0 {
+		return
+	}
+	keep := NewBitset(labyrinth.Rows * labyrinth.Cols)
+	floodRegion(labyrinth, best, keep, &queue)
+	for i := 0; i < labyrinth.Rows; i++ {
+		for j := 0; j < labyrinth.Cols; j++ {
+			if labyrinth.Map[i][j] == '.' && !keep.Get(i*labyrinth.Cols+j) {
+				labyrinth.Map[i][j] = '#'
+			}
+		}
+	}
+}
+
+// floodRegion 从 start 出发 BFS 标记同一区域的空地，返回区域大小
+// queue 按 cellIndex 保存格子，调用之间复用同一块缓冲区
+func floodRegion(labyrinth *Labyrinth, start Position, visited Bitset, queue *[]int32) int {
+	visited.Set(cellIndex(labyrinth, start))
+	q := append((*queue)[:0], int32(cellIndex(labyrinth, start)))
+	for head := 0; head < len(q); head++ {
+		row, col := int(q[head])/labyrinth.Cols, int(q[head])%labyrinth.Cols
+		for _, d := range Directions {
+			r, c := row+d.DRow, col+d.DCol
+			if IsEmptySpace(labyrinth, r, c) && !visited.Get(r*labyrinth.Cols+c) {
+				visited.Set(r*labyrinth.Cols + c)
+				q = append(q, int32(r*labyrinth.Cols+c))
+			}
+		}
+	}
+	*queue = q
+	return len(q)
+}
+
+// placePlayers 把玩家 0..n-1 随机放到空地上
+func placePlayers(labyrinth *Labyrinth, rng *rand.Rand, n int) error {
+	var empty []Position
+	for i := 0; i < labyrinth.Rows; i++ {
+		for j := 0; j < labyrinth.Cols; j++ {
+			if labyrinth.Map[i][j] == '.' {
+				empty = append(empty, Position{i, j})
+			}
+		}
+	}
+	// 至少保留一块空地，否则 IsConnected 找不到起点
+	if len(empty) <= n {
+		return errors.New("not enough empty space for players")
+	}
+	rng.Shuffle(len(empty), func(i, j int) {
+		empty[i], empty[j] = empty[j], empty[i]
+	})
+	for i := 0; i < n; i++ {
+		labyrinth.Map[empty[i].Row][empty[i].Col] = byte('0' + i)
+	}
+	return nil
+}
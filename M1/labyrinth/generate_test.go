@@ -0,0 +1,51 @@
+package labyrinth
+
+import "testing"
+
+// TestGenerate 测试各算法生成的地图连通且可复现
+func TestGenerate(t *testing.T) {
+	for _, algo := range []string{AlgoBacktracker, AlgoPrim, AlgoCave} {
+		opts := GenerateOptions{Rows: 21, Cols: 31, Seed: 42, Density: 0.4, Players: 3, Algorithm: algo}
+		lab, err := Generate(opts)
+		if err != nil {
+			t.Fatalf("Generate(%s) error: %v", algo, err)
+		}
+		if lab.Rows != 21 || lab.Cols != 31 {
+			t.Errorf("Generate(%s) rows=%d, cols=%d, expected 21x31", algo, lab.Rows, lab.Cols)
+		}
+		if err := IsConnected(lab); err != nil {
+			t.Errorf("Generate(%s) map is not connected: %v", algo, err)
+		}
+		for _, id := range []byte{'0', '1', '2'} {
+			if pos, _ := FindPlayer(lab, id); lab.Map[pos.Row][pos.Col] != id {
+				t.Errorf("Generate(%s) player %c missing", algo, id)
+			}
+		}
+
+		again, err := Generate(opts)
+		if err != nil {
+			t.Fatalf("Generate(%s) error: %v", algo, err)
+		}
+		for i := range lab.Map {
+			if string(lab.Map[i]) != string(again.Map[i]) {
+				t.Errorf("Generate(%s) is not reproducible for the same seed", algo)
+				break
+			}
+		}
+	}
+}
+
+// TestGenerateInvalid 测试非法参数
+func TestGenerateInvalid(t *testing.T) {
+	tests := []GenerateOptions{
+		{Rows: MaxRows + 1, Cols: 10, Algorithm: AlgoBacktracker},
+		{Rows: 2, Cols: 10, Algorithm: AlgoBacktracker},
+		{Rows: 10, Cols: 10, Players: 11, Algorithm: AlgoBacktracker},
+		{Rows: 10, Cols: 10, Algorithm: "unknown"},
+	}
+	for _, opts := range tests {
+		if _, err := Generate(opts); err == nil {
+			t.Errorf("Generate(%+v) should fail", opts)
+		}
+	}
+}
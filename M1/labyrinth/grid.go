@@ -0,0 +1,24 @@
+package labyrinth
+
+// Bitset 按格子编号 row*Cols+col 记录访问状态，每个格子占 1 bit
+type Bitset []uint64
+
+// NewBitset 创建可容纳 n 个格子的位图
+func NewBitset(n int) Bitset {
+	return make(Bitset, (n+63)/64)
+}
+
+// Get 读取第 i 位
+func (b Bitset) Get(i int) bool {
+	return b[i>>6]&(1<<(uint(i)&63)) != 0
+}
+
+// Set 设置第 i 位
+func (b Bitset) Set(i int) {
+	b[i>>6] |= 1 << (uint(i) & 63)
+}
+
+// cellIndex 格子编号
+func cellIndex(labyrinth *Labyrinth, p Position) int {
+	return p.Row*labyrinth.Cols + p.Col
+}
@@ -0,0 +1,325 @@
+package labyrinth
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+// HTTPServer 地图的 HTTP/JSON 接口：
+//
+//	GET  /map                 整张地图与所有玩家
+//	GET  /players/{id}        单个玩家
+//	POST /players/{id}/move   {"direction":"up"} 或 {"moves":["up","run:left"]}
+//	GET  /events              移动事件流（server-sent events）
+//
+// 与命令行相同，每个请求都持有 LockMap 重新加载地图文件，移动后用 CommitMap 提交，
+// 因此命令行与 bot 的移动也能看到；事件流只包含经过本服务器、并且已经提交的移动
+type HTTPServer struct {
+	mu      sync.Mutex
+	game    *Game
+	mapFile string
+	mux     *http.ServeMux
+	pending []MoveEvent                 // 本次请求中还没有提交的移动
+	streams map[chan MoveEvent]struct{} // /events 的连接
+}
+
+// saveError 提交地图失败，与加载失败区分开
+type saveError struct{ error }
+
+func (e saveError) Unwrap() error { return e.error }
+
+// moveRequest POST /players/{id}/move 的请求体
+type moveRequest struct {
+	Direction string   `json:"direction"`
+	Moves     []string `json:"moves"`
+}
+
+// apiPlayer 接口中的玩家
+type apiPlayer struct {
+	ID string `json:"id"`
+	Position
+	Keys     string `json:"keys,omitempty"`
+	Finished bool   `json:"finished,omitempty"`
+	Captured bool   `json:"captured,omitempty"`
+}
+
+// apiEvent 接口中的移动事件
+type apiEvent struct {
+	Player    string   `json:"player"`
+	Direction string   `json:"direction"`
+	From      Position `json:"from"`
+	To        Position `json:"to"`
+	Finished  bool     `json:"finished,omitempty"`
+}
+
+// apiError 错误响应，code 与命令行 --format json 的 error.code 相同
+type apiError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// NewHTTPServer 加载地图并检查连通性
+func NewHTTPServer(mapFile string) (*HTTPServer, error) {
+	game := &Game{}
+	if err := game.Load(mapFile); err != nil {
+		return nil, err
+	}
+	s := &HTTPServer{
+		game:    game,
+		mapFile: mapFile,
+		mux:     http.NewServeMux(),
+		streams: make(map[chan MoveEvent]struct{}),
+	}
+	// Move 在 withMap 中调用，回调执行时已经持有 s.mu
+	game.Subscribe(func(event MoveEvent) {
+		s.pending = append(s.pending, event)
+	})
+	s.mux.HandleFunc("GET /map", s.handleMap)
+	s.mux.HandleFunc("GET /players/{id}", s.handlePlayer)
+	s.mux.HandleFunc("POST /players/{id}/move", s.handleMove)
+	s.mux.HandleFunc("GET /events", s.handleEvents)
+	return s, nil
+}
+
+// ServeHTTP 实现 http.Handler
+func (s *HTTPServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// withMap 持有文件锁重新加载地图后执行 fn，save 为 true 且 fn 成功时提交，
+// 提交成功后才把移动推给 /events
+func (s *HTTPServer) withMap(save bool, fn func() error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pending = nil
+	lock, err := LockMap(s.mapFile)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+	if err := s.game.Load(s.mapFile); err != nil {
+		return err
+	}
+	if err := fn(); err != nil {
+		return err
+	}
+	if !save {
+		return nil
+	}
+	if err := s.game.Save(); err != nil {
+		return saveError{err}
+	}
+	for _, event := range s.pending {
+		for stream := range s.streams {
+			select {
+			case stream <- event:
+			default:
+			}
+		}
+	}
+	return nil
+}
+
+func (s *HTTPServer) handleMap(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Version int         `json:"version"`
+		Rows    int         `json:"rows"`
+		Cols    int         `json:"cols"`
+		Floors  int         `json:"floors"`
+		Map     []string    `json:"map"`
+		Players []apiPlayer `json:"players"`
+	}
+	err := s.withMap(false, func() error {
+		labyrinth := s.game.Labyrinth()
+		body.Version, body.Rows, body.Cols = labyrinth.Version, labyrinth.Rows, labyrinth.Cols
+		body.Floors = Floors(labyrinth)
+		for i := 0; i < labyrinth.Rows; i++ {
+			body.Map = append(body.Map, string(labyrinth.Map[i]))
+		}
+		body.Players = []apiPlayer{}
+		for _, player := range s.game.Players() {
+			body.Players = append(body.Players, toAPIPlayer(player))
+		}
+		return nil
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, body)
+}
+
+func (s *HTTPServer) handlePlayer(w http.ResponseWriter, r *http.Request) {
+	id, ok := playerParam(w, r)
+	if !ok {
+		return
+	}
+	var found *Player
+	err := s.withMap(false, func() error {
+		found = s.findPlayer(id)
+		return nil
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if found == nil {
+		writeJSON(w, http.StatusNotFound, map[string]apiError{
+			"error": {Code: "not_found", Message: fmt.Sprintf("player %c is not on the map", id)},
+		})
+		return
+	}
+	writeJSON(w, http.StatusOK, toAPIPlayer(*found))
+}
+
+func (s *HTTPServer) handleMove(w http.ResponseWriter, r *http.Request) {
+	id, ok := playerParam(w, r)
+	if !ok {
+		return
+	}
+	var req moveRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]apiError{
+			"error": {Code: "bad_args", Message: "invalid request body: " + err.Error()},
+		})
+		return
+	}
+	if (req.Direction == "") == (len(req.Moves) == 0) {
+		writeJSON(w, http.StatusBadRequest, map[string]apiError{
+			"error": {Code: "bad_args", Message: "give exactly one of direction and moves"},
+		})
+		return
+	}
+	var events []MoveEvent
+	var player *Player
+	err := s.withMap(true, func() error {
+		var err error
+		if req.Direction != "" {
+			var event MoveEvent
+			event, err = s.game.Move(id, req.Direction)
+			events = []MoveEvent{event}
+		} else {
+			events, err = s.game.MoveAll(id, req.Moves)
+		}
+		player = s.findPlayer(id)
+		return err
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	body := struct {
+		Events []apiEvent `json:"events"`
+		Player *apiPlayer `json:"player,omitempty"`
+	}{}
+	for _, event := range events {
+		body.Events = append(body.Events, toAPIEvent(event))
+	}
+	if player != nil {
+		p := toAPIPlayer(*player)
+		body.Player = &p
+	}
+	writeJSON(w, http.StatusOK, body)
+}
+
+// handleEvents 以 server-sent events 推送移动，慢客户端会丢失事件而不是拖住移动
+func (s *HTTPServer) handleEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	events := make(chan MoveEvent, 64)
+	s.mu.Lock()
+	s.streams[events] = struct{}{}
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.streams, events)
+		s.mu.Unlock()
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event := <-events:
+			data, _ := json.Marshal(toAPIEvent(event))
+			if _, err := fmt.Fprintf(w, "event: move\ndata: %s\n\n", data); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// findPlayer 地图上的玩家，不存在时返回 nil
+func (s *HTTPServer) findPlayer(id byte) *Player {
+	for _, player := range s.game.Players() {
+		if player.ID == id {
+			return &player
+		}
+	}
+	return nil
+}
+
+// playerParam 解析路径中的玩家 ID，非法时写入 400
+func playerParam(w http.ResponseWriter, r *http.Request) (byte, bool) {
+	id := r.PathValue("id")
+	if len(id) != 1 || !IsValidPlayer(id) {
+		writeJSON(w, http.StatusBadRequest, map[string]apiError{
+			"error": {Code: "bad_args", Message: fmt.Sprintf("invalid player %q", id)},
+		})
+		return 0, false
+	}
+	return id[0], true
+}
+
+// writeError 按错误类型选择状态码
+func writeError(w http.ResponseWriter, err error) {
+	status, code := http.StatusInternalServerError, "load_error"
+	switch {
+	case errors.Is(err, ErrInvalidMove):
+		status, code = http.StatusUnprocessableEntity, "invalid_move"
+	case errors.Is(err, ErrNotConnected):
+		code = "not_connected"
+	case errors.Is(err, ErrStaleMap):
+		status, code = http.StatusConflict, "save_error"
+	case errors.As(err, new(saveError)):
+		code = "save_error"
+	}
+	writeJSON(w, status, map[string]apiError{"error": {Code: code, Message: err.Error()}})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+func toAPIPlayer(player Player) apiPlayer {
+	return apiPlayer{
+		ID:       string(player.ID),
+		Position: player.Position,
+		Keys:     player.Keys,
+		Finished: player.Finished,
+		Captured: player.Captured,
+	}
+}
+
+func toAPIEvent(event MoveEvent) apiEvent {
+	return apiEvent{
+		Player:    string(event.Player),
+		Direction: event.Direction,
+		From:      event.From,
+		To:        event.To,
+		Finished:  event.Finished,
+	}
+}
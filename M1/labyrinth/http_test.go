@@ -0,0 +1,121 @@
+package labyrinth
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestHTTPServer 测试 HTTP 接口的查询、移动、错误码与事件流
+func TestHTTPServer(t *testing.T) {
+	mapFile := filepath.Join(t.TempDir(), "map.txt")
+	if err := os.WriteFile(mapFile, []byte("#####\n#0..#\n#..1#\n#####\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	server, err := NewHTTPServer(mapFile)
+	if err != nil {
+		t.Fatalf("NewHTTPServer() error: %v", err)
+	}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	get := func(path string, body any) int {
+		t.Helper()
+		resp, err := http.Get(ts.URL + path)
+		if err != nil {
+			t.Fatalf("GET %s error: %v", path, err)
+		}
+		defer resp.Body.Close()
+		json.NewDecoder(resp.Body).Decode(body)
+		return resp.StatusCode
+	}
+	post := func(path, request string, body any) int {
+		t.Helper()
+		resp, err := http.Post(ts.URL+path, "application/json", strings.NewReader(request))
+		if err != nil {
+			t.Fatalf("POST %s error: %v", path, err)
+		}
+		defer resp.Body.Close()
+		json.NewDecoder(resp.Body).Decode(body)
+		return resp.StatusCode
+	}
+
+	var m struct {
+		Rows    int
+		Map     []string
+		Players []apiPlayer
+	}
+	if status := get("/map", &m); status != http.StatusOK || m.Rows != 4 || len(m.Players) != 2 {
+		t.Fatalf("GET /map = %d %+v", status, m)
+	}
+
+	// 先连上事件流，再移动
+	events, err := http.Get(ts.URL + "/events")
+	if err != nil {
+		t.Fatalf("GET /events error: %v", err)
+	}
+	defer events.Body.Close()
+
+	var moved struct {
+		Events []apiEvent
+		Player *apiPlayer
+	}
+	if status := post("/players/0/move", `{"direction":"right"}`, &moved); status != http.StatusOK {
+		t.Fatalf("POST move = %d", status)
+	}
+	if moved.Player == nil || moved.Player.Position != (Position{1, 2}) {
+		t.Errorf("POST move player = %+v, expected (1, 2)", moved.Player)
+	}
+
+	var failed struct{ Error apiError }
+	if status := post("/players/0/move", `{"direction":"up"}`, &failed); status != http.StatusUnprocessableEntity || failed.Error.Code != "invalid_move" {
+		t.Errorf("POST move into wall = %d %+v", status, failed.Error)
+	}
+	if status := post("/players/x/move", `{"direction":"up"}`, &failed); status != http.StatusBadRequest {
+		t.Errorf("POST move for invalid player = %d, expected 400", status)
+	}
+	if status := get("/players/5", &failed); status != http.StatusNotFound {
+		t.Errorf("GET missing player = %d, expected 404", status)
+	}
+	var player apiPlayer
+	if status := get("/players/1", &player); status != http.StatusOK || player.Position != (Position{2, 3}) {
+		t.Errorf("GET /players/1 = %d %+v", status, player)
+	}
+
+	// 只有成功提交的移动进入事件流
+	done := make(chan string)
+	go func() {
+		scanner := bufio.NewScanner(events.Body)
+		for scanner.Scan() {
+			if data, ok := strings.CutPrefix(scanner.Text(), "data: "); ok {
+				done <- data
+				return
+			}
+		}
+	}()
+	select {
+	case data := <-done:
+		var event apiEvent
+		json.Unmarshal([]byte(data), &event)
+		if event.Player != "0" || event.Direction != "right" || event.To != (Position{1, 2}) {
+			t.Errorf("event = %+v", event)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no event received")
+	}
+
+	// 移动已经持久化到地图文件
+	lab := &Labyrinth{}
+	if err := LoadMap(lab, mapFile); err != nil {
+		t.Fatalf("LoadMap() error: %v", err)
+	}
+	if pos, _ := FindPlayer(lab, '0'); pos.Row != 1 || pos.Col != 2 {
+		t.Errorf("saved player at (%d, %d), expected (1, 2)", pos.Row, pos.Col)
+	}
+}
@@ -0,0 +1,456 @@
+package labyrinth
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// JournalSuffix 日志文件与地图文件放在一起：map.txt -> map.txt.journal.log
+const JournalSuffix = ".journal.log"
+
+// 日志动作
+const (
+	ActionMove = "move"
+	ActionUndo = "undo"
+	ActionRedo = "redo"
+)
+
+// JournalEntry 一条日志记录
+// undo/redo 记录的是被撤销/重做的那次移动本身，方向、坐标与附带修改保持原样
+type JournalEntry struct {
+	Time      time.Time
+	Action    string
+	Player    byte
+	Direction string
+	From      Position
+	To        Position
+	Effects   MoveEffects
+}
+
+// MoveEffects 一次移动在玩家位置之外的修改，撤销与重做时据此还原
+type MoveEffects struct {
+	Tile     byte     // 落点上被消耗的钥匙或门，落点变为空地
+	Key      byte     // 拾取进背包的钥匙
+	Exit     bool     // 这一步到达出口
+	Victim   byte     // tag 模式下被抓的玩家
+	VictimAt Position // 被抓玩家原来的位置
+	Counted  bool     // 规则模式下计入步数，以下字段只在规则模式下有效
+	NewScore bool     // 这一步第一次为玩家记步数与分数
+	Score    int      // 分数变化
+	Turn     byte     // 移动前的 @turn
+	NextTurn byte     // 移动后的 @turn
+	Over     bool     // 这一步结束了比赛
+	Winner   byte     // 比赛结束时的胜者，0 为平局
+}
+
+// JournalPath 地图对应的日志文件路径
+func JournalPath(mapFile string) string {
+	return mapFile + JournalSuffix
+}
+
+// ArchiveJournal 把地图的日志改名为 map.txt.journal.log.vVERSION 存档，返回存档路径，没有日志时返回空字符串
+// 规则重置比赛状态时调用：重置没有日志记录，之后的撤销与回放不能越过它
+func ArchiveJournal(mapFile string, version int) (string, error) {
+	path := JournalPath(mapFile)
+	archive := fmt.Sprintf("%s.v%d", path, version)
+	if err := os.Rename(path, archive); errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	} else if err != nil {
+		return "", err
+	}
+	return archive, nil
+}
+
+// String 日志行格式：时间 动作 玩家 方向 起点 终点 [附带修改...]
+func (e JournalEntry) String() string {
+	line := fmt.Sprintf("%s %s %c %s %d,%d %d,%d", e.Time.Format(time.RFC3339Nano), e.Action,
+		e.Player, e.Direction, e.From.Row, e.From.Col, e.To.Row, e.To.Col)
+	if effects := e.Effects.String(); effects != "" {
+		line += " " + effects
+	}
+	return line
+}
+
+// String 附带修改的日志格式，没有修改时为空：
+//
+//	tile=C key=C exit capture=ID@ROW,COL counted newscore score=N turn=A,B over=ID|-
+//
+// turn 中的 - 表示没有记录
+func (e MoveEffects) String() string {
+	var fields []string
+	if e.Tile != 0 {
+		fields = append(fields, "tile="+string(e.Tile))
+	}
+	if e.Key != 0 {
+		fields = append(fields, "key="+string(e.Key))
+	}
+	if e.Exit {
+		fields = append(fields, "exit")
+	}
+	if e.Victim != 0 {
+		fields = append(fields, fmt.Sprintf("capture=%c@%d,%d", e.Victim, e.VictimAt.Row, e.VictimAt.Col))
+	}
+	if e.Counted {
+		fields = append(fields, "counted")
+	}
+	if e.NewScore {
+		fields = append(fields, "newscore")
+	}
+	if e.Score != 0 {
+		fields = append(fields, fmt.Sprintf("score=%d", e.Score))
+	}
+	if e.Turn != 0 || e.NextTurn != 0 {
+		fields = append(fields, "turn="+playerOrDash(e.Turn)+","+playerOrDash(e.NextTurn))
+	}
+	if e.Over {
+		fields = append(fields, "over="+playerOrDash(e.Winner))
+	}
+	return strings.Join(fields, " ")
+}
+
+func playerOrDash(id byte) string {
+	if id == 0 {
+		return "-"
+	}
+	return string(id)
+}
+
+// parseMoveEffects 解析日志行末尾的附带修改
+func parseMoveEffects(fields []string) (MoveEffects, error) {
+	var e MoveEffects
+	for _, field := range fields {
+		invalid := fmt.Errorf("invalid journal effect %q", field)
+		key, value, _ := strings.Cut(field, "=")
+		switch key {
+		case "tile", "key":
+			if len(value) != 1 || !(IsKey(value[0]) || (key == "tile" && IsDoor(value[0]))) {
+				return e, invalid
+			}
+			if key == "tile" {
+				e.Tile = value[0]
+			} else {
+				e.Key = value[0]
+			}
+		case "exit":
+			e.Exit = true
+		case "capture":
+			id, at, ok := strings.Cut(value, "@")
+			if !ok || len(id) != 1 || !IsValidPlayer(id) {
+				return e, invalid
+			}
+			p, err := ParsePosition(at)
+			if err != nil {
+				return e, invalid
+			}
+			e.Victim, e.VictimAt = id[0], p
+		case "counted":
+			e.Counted = true
+		case "newscore":
+			e.NewScore = true
+		case "score":
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return e, invalid
+			}
+			e.Score = n
+		case "turn":
+			before, after, ok := strings.Cut(value, ",")
+			if !ok || !parsePlayerOrDash(before, &e.Turn) || !parsePlayerOrDash(after, &e.NextTurn) {
+				return e, invalid
+			}
+		case "over":
+			if !parsePlayerOrDash(value, &e.Winner) {
+				return e, invalid
+			}
+			e.Over = true
+		default:
+			return e, invalid
+		}
+	}
+	return e, nil
+}
+
+func parsePlayerOrDash(s string, id *byte) bool {
+	if s == "-" {
+		*id = 0
+		return true
+	}
+	if len(s) != 1 || !IsValidPlayer(s) {
+		return false
+	}
+	*id = s[0]
+	return true
+}
+
+// ParseJournalEntry 解析一行日志
+func ParseJournalEntry(line string) (JournalEntry, error) {
+	fields := strings.Fields(line)
+	if len(fields) < 6 {
+		return JournalEntry{}, fmt.Errorf("invalid journal entry %q", line)
+	}
+	t, err := time.Parse(time.RFC3339Nano, fields[0])
+	if err != nil {
+		return JournalEntry{}, fmt.Errorf("invalid journal time %q", fields[0])
+	}
+	switch fields[1] {
+	case ActionMove, ActionUndo, ActionRedo:
+	default:
+		return JournalEntry{}, fmt.Errorf("invalid journal action %q", fields[1])
+	}
+	if len(fields[2]) != 1 || !IsValidPlayer(fields[2]) {
+		return JournalEntry{}, fmt.Errorf("invalid journal player %q", fields[2])
+	}
+	from, err := ParsePosition(fields[4])
+	if err != nil {
+		return JournalEntry{}, err
+	}
+	to, err := ParsePosition(fields[5])
+	if err != nil {
+		return JournalEntry{}, err
+	}
+	effects, err := parseMoveEffects(fields[6:])
+	if err != nil {
+		return JournalEntry{}, err
+	}
+	return JournalEntry{
+		Time:      t,
+		Action:    fields[1],
+		Player:    byte(fields[2][0]),
+		Direction: fields[3],
+		From:      from,
+		To:        to,
+		Effects:   effects,
+	}, nil
+}
+
+// AppendJournal 追加一条日志并 fsync
+func AppendJournal(filename string, entry JournalEntry) error {
+	file, err := os.OpenFile(filename, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if _, err := file.WriteString(entry.String() + "\n"); err != nil {
+		return err
+	}
+	return file.Sync()
+}
+
+// ReadJournal 读取全部日志，文件不存在时返回空日志
+func ReadJournal(filename string) ([]JournalEntry, error) {
+	lines, err := readFile(filename)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]JournalEntry, 0, len(lines))
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		entry, err := ParseJournalEntry(line)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// JournalStacks 根据日志计算当前已生效的移动（可撤销）与已撤销的移动（可重做）
+func JournalStacks(entries []JournalEntry) (applied, undone []JournalEntry) {
+	for _, e := range entries {
+		switch e.Action {
+		case ActionMove:
+			applied = append(applied, e)
+			undone = undone[:0]
+		case ActionUndo:
+			if len(applied) > 0 {
+				undone = append(undone, applied[len(applied)-1])
+				applied = applied[:len(applied)-1]
+			}
+		case ActionRedo:
+			if len(undone) > 0 {
+				applied = append(applied, undone[len(undone)-1])
+				undone = undone[:len(undone)-1]
+			}
+		}
+	}
+	return applied, undone
+}
+
+// ApplyEntry 在地图上执行一条日志；reverse 为 true 时反向执行
+// 位置之外的修改（钥匙、门、出口、抓人与规则状态）按 Effects 一并执行或还原
+// 会检查玩家与被抓玩家确实在记录的位置，日志与地图不一致时返回错误
+func ApplyEntry(labyrinth *Labyrinth, entry JournalEntry, reverse bool) error {
+	if reverse != (entry.Action == ActionUndo) {
+		return undoEntry(labyrinth, entry)
+	}
+	return redoEntry(labyrinth, entry)
+}
+
+// redoEntry 重新执行日志中的移动
+func redoEntry(labyrinth *Labyrinth, entry JournalEntry) error {
+	from, to, e := entry.From, entry.To, entry.Effects
+	mismatch := fmt.Errorf("journal does not match map at %s", entry)
+	if !inBounds(labyrinth, from) || !inBounds(labyrinth, to) || labyrinth.Map[from.Row][from.Col] != entry.Player {
+		return mismatch
+	}
+	if e.Victim != 0 && (!inBounds(labyrinth, e.VictimAt) || labyrinth.Map[e.VictimAt.Row][e.VictimAt.Col] != e.Victim) {
+		return mismatch
+	}
+	switch {
+	case e.Victim != 0 && e.VictimAt == to:
+		// 落点上是被抓的玩家
+	case e.Tile != 0:
+		if labyrinth.Map[to.Row][to.Col] != e.Tile {
+			return mismatch
+		}
+	case !IsEmptySpace(labyrinth, to.Row, to.Col):
+		return mismatch
+	}
+
+	if e.Victim != 0 {
+		labyrinth.Map[e.VictimAt.Row][e.VictimAt.Col] = TileUnder(labyrinth, e.Victim)
+		delete(labyrinth.Under, e.Victim)
+	}
+	// 与 MovePlayer 一样保留楼梯、传送门与出口，钥匙与门被消耗
+	labyrinth.Map[from.Row][from.Col] = TileUnder(labyrinth, entry.Player)
+	placePlayer(labyrinth, entry.Player, to, labyrinth.Map[to.Row][to.Col])
+	if e.Key != 0 {
+		addKey(labyrinth, entry.Player, e.Key)
+	}
+	if e.Exit {
+		if labyrinth.Finished == nil {
+			labyrinth.Finished = make(map[byte]bool)
+		}
+		labyrinth.Finished[entry.Player] = true
+	}
+	if game := labyrinth.Game; game != nil {
+		if e.Victim != 0 {
+			game.Captured[e.Victim] = true
+		}
+		if e.Counted {
+			game.Moves[entry.Player]++
+			game.Scores[entry.Player] += e.Score
+			game.Turn = e.NextTurn
+		}
+		if e.Over {
+			game.Over, game.Winner = true, e.Winner
+		}
+	}
+	return nil
+}
+
+// undoEntry 撤销日志中的移动，还原被消耗的地块、背包、被抓的玩家与规则状态
+func undoEntry(labyrinth *Labyrinth, entry JournalEntry) error {
+	from, to, e := entry.From, entry.To, entry.Effects
+	mismatch := fmt.Errorf("journal does not match map at %s", entry)
+	if !inBounds(labyrinth, from) || !inBounds(labyrinth, to) || labyrinth.Map[to.Row][to.Col] != entry.Player ||
+		!IsEmptySpace(labyrinth, from.Row, from.Col) {
+		return mismatch
+	}
+	if e.Victim != 0 && e.VictimAt != to && (!inBounds(labyrinth, e.VictimAt) || !IsEmptySpace(labyrinth, e.VictimAt.Row, e.VictimAt.Col)) {
+		return mismatch
+	}
+
+	left := TileUnder(labyrinth, entry.Player)
+	if e.Tile != 0 {
+		left = e.Tile
+	}
+	labyrinth.Map[to.Row][to.Col] = left
+	placePlayer(labyrinth, entry.Player, from, labyrinth.Map[from.Row][from.Col])
+	if e.Victim != 0 {
+		placePlayer(labyrinth, e.Victim, e.VictimAt, labyrinth.Map[e.VictimAt.Row][e.VictimAt.Col])
+	}
+	if e.Key != 0 {
+		labyrinth.Inventory[entry.Player] = strings.Replace(labyrinth.Inventory[entry.Player], string(e.Key), "", 1)
+		if labyrinth.Inventory[entry.Player] == "" {
+			delete(labyrinth.Inventory, entry.Player)
+		}
+	}
+	if e.Exit {
+		delete(labyrinth.Finished, entry.Player)
+	}
+	if game := labyrinth.Game; game != nil {
+		if e.Victim != 0 {
+			delete(game.Captured, e.Victim)
+		}
+		if e.Counted {
+			game.Moves[entry.Player]--
+			game.Scores[entry.Player] -= e.Score
+			if e.NewScore {
+				delete(game.Moves, entry.Player)
+				delete(game.Scores, entry.Player)
+			}
+			game.Turn = e.Turn
+		}
+		if e.Over {
+			game.Over, game.Winner = false, 0
+		}
+	}
+	return nil
+}
+
+// placePlayer 把玩家放到 p，tile 为 p 原来的地块；楼梯、传送门与出口记为玩家脚下的地块
+func placePlayer(labyrinth *Labyrinth, playerID byte, p Position, tile byte) {
+	labyrinth.Map[p.Row][p.Col] = playerID
+	if IsStairs(tile) || IsTeleporter(tile) || tile == ExitTile {
+		if labyrinth.Under == nil {
+			labyrinth.Under = make(map[byte]byte)
+		}
+		labyrinth.Under[playerID] = tile
+	} else {
+		delete(labyrinth.Under, playerID)
+	}
+}
+
+func inBounds(labyrinth *Labyrinth, p Position) bool {
+	return p.Row >= 0 && p.Row < labyrinth.Rows && p.Col >= 0 && p.Col < labyrinth.Cols
+}
+
+// UndoMove 在地图上撤销最后一次生效的移动，返回对应的 undo 记录
+// 不写日志：调用方需要在保存地图后用 AppendJournal 写入返回的记录（Game.Save 会这样做），且只写一次
+func UndoMove(labyrinth *Labyrinth, journalFile string) (*JournalEntry, error) {
+	entries, err := ReadJournal(journalFile)
+	if err != nil {
+		return nil, err
+	}
+	applied, _ := JournalStacks(entries)
+	if len(applied) == 0 {
+		return nil, errors.New("nothing to undo")
+	}
+	entry := applied[len(applied)-1]
+	if err := ApplyEntry(labyrinth, entry, true); err != nil {
+		return nil, err
+	}
+	entry.Action = ActionUndo
+	entry.Time = time.Now()
+	return &entry, nil
+}
+
+// RedoMove 在地图上重做最后一次撤销的移动，返回对应的 redo 记录
+// 与 UndoMove 一样不写日志，由调用方写入返回的记录
+func RedoMove(labyrinth *Labyrinth, journalFile string) (*JournalEntry, error) {
+	entries, err := ReadJournal(journalFile)
+	if err != nil {
+		return nil, err
+	}
+	_, undone := JournalStacks(entries)
+	if len(undone) == 0 {
+		return nil, errors.New("nothing to redo")
+	}
+	entry := undone[len(undone)-1]
+	if err := ApplyEntry(labyrinth, entry, false); err != nil {
+		return nil, err
+	}
+	entry.Action = ActionRedo
+	entry.Time = time.Now()
+	return &entry, nil
+}
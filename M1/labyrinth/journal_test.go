@@ -0,0 +1,191 @@
+package labyrinth
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestJournalEntryRoundTrip 测试日志行的格式化与解析
+func TestJournalEntryRoundTrip(t *testing.T) {
+	entry := JournalEntry{
+		Time:      time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC),
+		Action:    ActionMove,
+		Player:    '3',
+		Direction: "left",
+		From:      Position{2, 5},
+		To:        Position{2, 4},
+	}
+	parsed, err := ParseJournalEntry(entry.String())
+	if err != nil {
+		t.Fatalf("ParseJournalEntry() error: %v", err)
+	}
+	if parsed != entry {
+		t.Errorf("ParseJournalEntry() = %+v, expected %+v", parsed, entry)
+	}
+	if _, err := ParseJournalEntry("garbage"); err == nil {
+		t.Error("ParseJournalEntry() should reject malformed lines")
+	}
+}
+
+// TestUndoRedo 测试撤销与重做
+func TestUndoRedo(t *testing.T) {
+	journal := filepath.Join(t.TempDir(), "map.txt"+JournalSuffix)
+	lab := newTestLabyrinth(
+		"0..",
+		"...",
+	)
+	moves := []string{"right", "down"}
+	for _, dir := range moves {
+		from, _ := FindPlayer(lab, '0')
+		if err := MovePlayer(lab, '0', dir); err != nil {
+			t.Fatalf("MovePlayer(%s) error: %v", dir, err)
+		}
+		to, _ := FindPlayer(lab, '0')
+		entry := JournalEntry{Time: time.Now(), Action: ActionMove, Player: '0', Direction: dir, From: *from, To: *to}
+		if err := AppendJournal(journal, entry); err != nil {
+			t.Fatalf("AppendJournal() error: %v", err)
+		}
+	}
+
+	entry, err := UndoMove(lab, journal)
+	if err != nil {
+		t.Fatalf("UndoMove() error: %v", err)
+	}
+	AppendJournal(journal, *entry)
+	if pos, _ := FindPlayer(lab, '0'); *pos != (Position{0, 1}) {
+		t.Errorf("after undo player at %v, expected (0, 1)", *pos)
+	}
+
+	entry, err = RedoMove(lab, journal)
+	if err != nil {
+		t.Fatalf("RedoMove() error: %v", err)
+	}
+	AppendJournal(journal, *entry)
+	if pos, _ := FindPlayer(lab, '0'); *pos != (Position{1, 1}) {
+		t.Errorf("after redo player at %v, expected (1, 1)", *pos)
+	}
+	if _, err := RedoMove(lab, journal); err == nil {
+		t.Error("RedoMove() should fail with nothing to redo")
+	}
+
+	// 倒放整个日志应回到初始地图
+	entries, err := ReadJournal(journal)
+	if err != nil {
+		t.Fatalf("ReadJournal() error: %v", err)
+	}
+	for i := len(entries) - 1; i >= 0; i-- {
+		if err := ApplyEntry(lab, entries[i], true); err != nil {
+			t.Fatalf("ApplyEntry() error: %v", err)
+		}
+	}
+	if string(lab.Map[0]) != "0.." || string(lab.Map[1]) != "..." {
+		t.Errorf("rewound map = %q, %q", string(lab.Map[0]), string(lab.Map[1]))
+	}
+}
+
+// TestMoveEffectsRoundTrip 测试附带修改写入日志行后可以解析回来
+func TestMoveEffectsRoundTrip(t *testing.T) {
+	entry := JournalEntry{
+		Time:      time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC),
+		Action:    ActionUndo,
+		Player:    '0',
+		Direction: "right",
+		From:      Position{0, 0},
+		To:        Position{0, 1},
+		Effects: MoveEffects{
+			Tile: 'a', Key: 'a', Exit: true, Victim: '1', VictimAt: Position{0, 1},
+			Counted: true, NewScore: true, Score: 11, Turn: '0', NextTurn: '2', Over: true,
+		},
+	}
+	parsed, err := ParseJournalEntry(entry.String())
+	if err != nil {
+		t.Fatalf("ParseJournalEntry(%q) error: %v", entry.String(), err)
+	}
+	if parsed != entry {
+		t.Errorf("ParseJournalEntry() = %+v, expected %+v", parsed, entry)
+	}
+	if _, err := ParseJournalEntry(entry.String() + " bogus"); err == nil {
+		t.Error("ParseJournalEntry() should reject unknown effects")
+	}
+}
+
+// playAndUndo 在地图文件上依次移动并保存，再全部撤销、全部重做，返回每个阶段的地图文件内容
+func playAndUndo(t *testing.T, content string, player byte, moves ...string) (initial, played, undone, redone string) {
+	t.Helper()
+	mapFile := filepath.Join(t.TempDir(), "map.txt")
+	if err := os.WriteFile(mapFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	game := &Game{}
+	if err := game.Load(mapFile); err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	// 只比较地图与元数据，去掉每次提交都会变化的版本号
+	snapshot := func() string {
+		lab := &Labyrinth{}
+		if err := LoadMap(lab, mapFile); err != nil {
+			t.Fatalf("LoadMap() error: %v", err)
+		}
+		lab.Version = 0
+		SaveMap(lab, mapFile+".cmp")
+		saved, _ := os.ReadFile(mapFile + ".cmp")
+		return string(saved)
+	}
+	initial = snapshot()
+	for _, dir := range moves {
+		if _, err := game.Move(player, dir); err != nil {
+			t.Fatalf("Move(%s) error: %v", dir, err)
+		}
+		if err := game.Save(); err != nil {
+			t.Fatalf("Save() error: %v", err)
+		}
+	}
+	played = snapshot()
+	for range moves {
+		if _, err := game.Undo(); err != nil {
+			t.Fatalf("Undo() error: %v", err)
+		}
+		if err := game.Save(); err != nil {
+			t.Fatalf("Save() error: %v", err)
+		}
+	}
+	undone = snapshot()
+	for range moves {
+		if _, err := game.Redo(); err != nil {
+			t.Fatalf("Redo() error: %v", err)
+		}
+		if err := game.Save(); err != nil {
+			t.Fatalf("Save() error: %v", err)
+		}
+	}
+	redone = snapshot()
+	return initial, played, undone, redone
+}
+
+// TestUndoRestoresEffects 测试撤销还原钥匙、门、抓人、出口与规则状态，重做再次执行
+func TestUndoRestoresEffects(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		player  byte
+		moves   []string
+	}{
+		{"key and door", "#0a.A.#\n", '0', []string{"right", "right", "right"}},
+		{"tag capture", "012.\n....\n@rules tag turns\n", '0', []string{"right"}},
+		{"race to exit", "0E.1\n....\n@rules race\n", '0', []string{"right"}},
+	}
+	for _, tt := range tests {
+		initial, played, undone, redone := playAndUndo(t, tt.content, tt.player, tt.moves...)
+		if played == initial {
+			t.Errorf("%s: moves did not change the map", tt.name)
+		}
+		if undone != initial {
+			t.Errorf("%s: after undo map =\n%s\nexpected\n%s", tt.name, undone, initial)
+		}
+		if redone != played {
+			t.Errorf("%s: after redo map =\n%s\nexpected\n%s", tt.name, redone, played)
+		}
+	}
+}
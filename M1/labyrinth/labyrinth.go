@@ -1,123 +1,50 @@
-package main
+// Package labyrinth 迷宫游戏引擎：地图读写、移动规则、寻路与多人对战，命令行在 cmd/labyrinth
+package labyrinth
 
 import (
 	"bufio"
+	"bytes"
 	"errors"
-	"flag"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
 	"strconv"
 )
 
 const (
-	MaxRows     = 100
-	MaxCols     = 100
+	MaxRows     = 10000
+	MaxCols     = 10000
 	VersionInfo = "Labyrinth Game"
 )
 
+// 可以用 errors.Is 判断的错误
+var (
+	ErrInvalidMove  = errors.New("invalid move")
+	ErrNotConnected = errors.New("map is not connected")
+	ErrMapTooLarge  = errors.New("map is too large")
+)
+
 // Labyrinth 迷宫结构
 type Labyrinth struct {
-	Map  [][]rune
-	Rows int
-	Cols int
-}
-
-// Position 位置结构
-type Position struct {
-	Row int
-	Col int
-}
+	Map     [][]byte // 每个格子一个字节
+	Rows    int
+	Cols    int
+	Version int // 每次提交移动加一，用于发现其他进程的并发修改
 
-func main() {
-	// 定义命令行参数
-	mapFile := flag.String("map", "/Users/qiye/home/2025/github/GolandProjects/os-2025/M1/labyrinth/maps/map.txt", "Map file path")
-	mapFileShort := flag.String("m", "", "Map file path (short)")
-	playerID := flag.String("player", "1", "Player ID (0-9)")
-	playerIDShort := flag.String("p", "", "Player ID (short)")
-	moveDir := flag.String("move", "up", "Move direction (up/down/left/right)")
-	version := flag.Bool("version", false, "Show version information")
+	FloorRows int // 多层地图每层的行数，单层地图为 0
 
-	flag.Parse()
-
-	// 处理 --version
-	if *version {
-		if flag.NArg() > 0 || len(os.Args) > 2 {
-			printUsage()
-			os.Exit(1)
-		}
-		fmt.Println(VersionInfo)
-		os.Exit(0)
-	}
-
-	// 合并短参数和长参数
-	if *mapFileShort != "" {
-		mapFile = mapFileShort
-	}
-	if *playerIDShort != "" {
-		playerID = playerIDShort
-	}
+	Inventory map[byte]string // 玩家持有的钥匙
+	Under     map[byte]byte   // 玩家脚下的地块（传送门、出口）
+	Finished  map[byte]bool   // 已经到达出口的玩家
 
-	// 检查未知参数
-	if flag.NArg() > 0 {
-		printUsage()
-		os.Exit(1)
-	}
-
-	// 1. 验证参数
-	if *mapFile == "" || *playerID == "" || len(*playerID) != 1 || *moveDir == "" {
-		printUsage()
-		os.Exit(1)
-	}
-	if !IsValidPlayer(*playerID) {
-		printUsage()
-		os.Exit(1)
-	}
-	// 2. 加载地图
-	labyrinth := &Labyrinth{}
-	err := LoadMap(labyrinth, *mapFile)
-	if err != nil {
-		fmt.Println("Error loading map:", err)
-		os.Exit(1)
-	}
-	err = IsConnected(labyrinth)
-	if err != nil {
-		fmt.Println("Error checking connectivity:", err)
-		os.Exit(1)
-	}
-	// 3. 处理玩家查询或移动
-	playerid := rune((*playerID)[0])
-	postion, err := FindPlayer(labyrinth, playerid)
-	if err != nil {
-		fmt.Println("Error finding player:", err)
-		os.Exit(1)
-	}
-	fmt.Printf("Player found at (%d, %d)", postion.Row, postion.Col)
-	err = MovePlayer(labyrinth, playerid, *moveDir)
-	if err != nil {
-		fmt.Println("Error moving player:", err)
-		os.Exit(1)
-	}
-	postion, err = FindPlayer(labyrinth, playerid)
-	if err != nil {
-		fmt.Println("Error finding player:", err)
-		os.Exit(1)
-	}
-	fmt.Printf("player new position at (%d,%d)\n", postion.Row, postion.Col)
-	// 4. 保存地图（如果有移动）
-	err = SaveMap(labyrinth, *mapFile)
-	if err != nil {
-		fmt.Println("Error saving map:", err)
-		os.Exit(1)
-	}
-	os.Exit(0)
+	Game *GameState // 规则层状态，没有 @rules 时为 nil
 }
 
-func printUsage() {
-	fmt.Println("Usage:")
-	fmt.Println("  labyrinth --map map.txt --player id")
-	fmt.Println("  labyrinth -m map.txt -p id")
-	fmt.Println("  labyrinth --map map.txt --player id --move direction")
-	fmt.Println("  labyrinth --version")
+// Position 位置结构
+type Position struct {
+	Row int `json:"row"`
+	Col int `json:"col"`
 }
 
 // IsValidPlayer 检查玩家ID是否有效（0-9）
@@ -137,29 +64,76 @@ func IsValidPlayer(playerID string) bool {
 func LoadMap(labyrinth *Labyrinth, filename string) error {
 	// 提示：
 	// 1. 打开文件
-	// 2. 逐行读取
-	lines, err := readFile(filename)
+	file, err := os.Open(filename)
 	if err != nil {
 		return err
 	}
-	// 如果地图过大，则返回错误
-	if len(lines) > MaxRows || len(lines[0]) > MaxCols {
-		return errors.New("map is too large")
+	defer file.Close()
+	// 2. 逐行读取，每行直接作为一个 byte 切片，不保留整份文件的副本
+	// 地图之后是以 '@' 开头的元数据行
+	reader := bufio.NewReaderSize(file, 1<<16)
+	var rows [][]byte
+	var meta []string
+	floorStart, floorRows := 0, 0
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(line) > 0 {
+			line = bytes.TrimSuffix(bytes.TrimSuffix(line, []byte("\n")), []byte("\r"))
+			switch {
+			case len(meta) > 0 || bytes.HasPrefix(line, []byte(MetaPrefix)):
+				meta = append(meta, string(line))
+			case string(line) == FloorSeparator:
+				// 楼层之间插入一行墙
+				if err := checkFloorHeight(len(rows)-floorStart, &floorRows); err != nil {
+					return err
+				}
+				if len(rows) >= MaxRows {
+					return ErrMapTooLarge
+				}
+				rows = append(rows, bytes.Repeat([]byte{'#'}, len(rows[0])))
+				floorStart = len(rows)
+			case len(rows) >= MaxRows || len(line) > MaxCols:
+				// 如果地图过大，则返回错误
+				return ErrMapTooLarge
+			case len(rows) > 0 && len(line) != len(rows[0]):
+				return fmt.Errorf("row %d has %d columns, expected %d", len(rows), len(line), len(rows[0]))
+			default:
+				rows = append(rows, line)
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if len(rows) == 0 {
+		return errors.New("map is empty")
 	}
-	// 3. 将每行转换为 rune 切片
-	runes := make([][]rune, len(lines))
-	for i, line := range lines {
-		runes[i] = []rune(line)
+	if floorRows > 0 {
+		if err := checkFloorHeight(len(rows)-floorStart, &floorRows); err != nil {
+			return err
+		}
 	}
-	// 4. 更新 labyrinth.Map, labyrinth.Rows, labyrinth.Cols
-	labyrinth.Map = runes
-	labyrinth.Rows = len(runes)
-	labyrinth.Cols = len(runes[0])
-	return nil
+	// 3. 更新 labyrinth.Map, labyrinth.Rows, labyrinth.Cols
+	labyrinth.Map = rows
+	labyrinth.Rows = len(rows)
+	labyrinth.Cols = len(rows[0])
+	labyrinth.Version = 0
+	labyrinth.FloorRows = floorRows
+	labyrinth.Inventory = make(map[byte]string)
+	labyrinth.Under = make(map[byte]byte)
+	labyrinth.Finished = make(map[byte]bool)
+	labyrinth.Game = nil
+	if err := parseMeta(labyrinth, meta); err != nil {
+		return err
+	}
+	return checkTiles(labyrinth)
 }
 
 // FindPlayer 在地图中查找指定玩家的位置
-func FindPlayer(labyrinth *Labyrinth, playerID rune) (*Position, error) {
+func FindPlayer(labyrinth *Labyrinth, playerID byte) (*Position, error) {
 	// 提示：遍历地图，找到与 playerID 匹配的位置
 	// 如果找不到，返回 Position{-1, -1}
 	for i := 0; i < labyrinth.Rows; i++ {
@@ -192,18 +166,20 @@ func FindFirstEmptySpace(labyrinth *Labyrinth) (*Position, error) {
 }
 
 // IsEmptySpace 检查指定位置是否为空
+// 钥匙、传送门和出口也可以直接走上去；门需要钥匙，见 enterTile
 func IsEmptySpace(labyrinth *Labyrinth, row, col int) bool {
 	// 提示：
 	// 1. 检查边界
 	// 2. 检查该位置是否为 '.'
-	if row >= 0 && row < labyrinth.Rows && col >= 0 && col < labyrinth.Cols && labyrinth.Map[row][col] == '.' {
-		return true
+	if row < 0 || row >= labyrinth.Rows || col < 0 || col >= labyrinth.Cols {
+		return false
 	}
-	return false
+	ch := labyrinth.Map[row][col]
+	return ch == '.' || IsKey(ch) || IsTeleporter(ch) || IsStairs(ch) || ch == ExitTile
 }
 
 // MovePlayer 移动玩家到指定方向
-func MovePlayer(labyrinth *Labyrinth, playerID rune, direction string) error {
+func MovePlayer(labyrinth *Labyrinth, playerID byte, direction string) error {
 	// 提示：
 	// 1. 找到玩家当前位置
 	// 2. 根据方向计算新位置
@@ -215,6 +191,9 @@ func MovePlayer(labyrinth *Labyrinth, playerID rune, direction string) error {
 	if err != nil {
 		return err
 	}
+	if labyrinth.Finished[playerID] {
+		return fmt.Errorf("%w: player has reached the exit", ErrInvalidMove)
+	}
 	row, col := p.Row, p.Col
 	var newPosition Position
 	switch direction {
@@ -226,12 +205,31 @@ func MovePlayer(labyrinth *Labyrinth, playerID rune, direction string) error {
 		newPosition = Position{row, col - 1}
 	case "right":
 		newPosition = Position{row, col + 1}
+	case DirectionUpstairs, DirectionDownstairs:
+		newPosition, err = stairTarget(labyrinth, playerID, *p, direction)
+		if err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("%w: invalid direction %q", ErrInvalidMove, direction)
 	}
-	if !IsEmptySpace(labyrinth, newPosition.Row, newPosition.Col) {
-		return errors.New("invalid move")
+	newPosition, under, err := enterTile(labyrinth, playerID, newPosition)
+	if err != nil {
+		return err
+	}
+	// 离开时恢复脚下的地块
+	if labyrinth.Map[row][col] == playerID {
+		labyrinth.Map[row][col] = TileUnder(labyrinth, playerID)
 	}
-	labyrinth.Map[row][col] = '.'
 	labyrinth.Map[newPosition.Row][newPosition.Col] = playerID
+	if under == '.' {
+		delete(labyrinth.Under, playerID)
+	} else {
+		if labyrinth.Under == nil {
+			labyrinth.Under = make(map[byte]byte)
+		}
+		labyrinth.Under[playerID] = under
+	}
 	return nil
 }
 
@@ -240,15 +238,26 @@ func SaveMap(labyrinth *Labyrinth, filename string) error {
 	// 提示：
 	// 1. 创建或覆盖文件
 	// 2. 逐行写入地图内容
-	lines := make([]string, labyrinth.Rows)
-	for i := 0; i < labyrinth.Rows; i++ {
-		lines[i] = string(labyrinth.Map[i])
-	}
-	err := writeFile(filename, lines)
-	if err != nil {
-		return err
-	}
-	return nil
+	return writeFileFunc(filename, func(writer *bufio.Writer) error {
+		for i := 0; i < labyrinth.Rows; i++ {
+			line := labyrinth.Map[i]
+			if isFloorSeparator(labyrinth, i) {
+				line = []byte(FloorSeparator)
+			}
+			if _, err := writer.Write(line); err != nil {
+				return err
+			}
+			if err := writer.WriteByte('\n'); err != nil {
+				return err
+			}
+		}
+		for _, line := range formatMeta(labyrinth) {
+			if _, err := writer.WriteString(line + "\n"); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
 }
 
 // DFS 深度优先搜索，用于检查连通性
@@ -256,16 +265,20 @@ func DFS(labyrinth *Labyrinth, row, col int, visited [][]bool) {
 	// 提示：
 	// 1. 检查边界和访问状态
 	// 2. 标记当前位置为已访问
-	// 3. 递归访问四个方向的邻居
-	if row < 0 || row >= labyrinth.Rows || col < 0 || col >= labyrinth.Cols || visited[row][col] || labyrinth.Map[row][col] == '#' {
-		return
+	// 3. 访问四个方向的邻居
+	// 用显式栈代替递归，大地图上递归深度可达格子数
+	stack := []Position{{row, col}}
+	for len(stack) > 0 {
+		p := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if p.Row < 0 || p.Row >= labyrinth.Rows || p.Col < 0 || p.Col >= labyrinth.Cols || visited[p.Row][p.Col] || labyrinth.Map[p.Row][p.Col] == '#' {
+			continue
+		}
+		visited[p.Row][p.Col] = true
+		for _, d := range Directions {
+			stack = append(stack, Position{p.Row + d.DRow, p.Col + d.DCol})
+		}
 	}
-	visited[row][col] = true
-	DFS(labyrinth, row-1, col, visited)
-	DFS(labyrinth, row+1, col, visited)
-	DFS(labyrinth, row, col-1, visited)
-	DFS(labyrinth, row, col+1, visited)
-	return
 }
 
 // IsConnected 检查所有空位置是否连通
@@ -278,15 +291,14 @@ func IsConnected(labyrinth *Labyrinth) error {
 	if err != nil {
 		return err
 	}
-	visited := make([][]bool, labyrinth.Rows)
-	for i := 0; i < labyrinth.Rows; i++ {
-		visited[i] = make([]bool, labyrinth.Cols)
-	}
-	DFS(labyrinth, position.Row, position.Col, visited)
+	// 门只有在能拿到钥匙时才算通路
+	visited := reachable(labyrinth, *position)
 	for i := 0; i < labyrinth.Rows; i++ {
+		row := labyrinth.Map[i]
 		for j := 0; j < labyrinth.Cols; j++ {
-			if labyrinth.Map[i][j] == '.' && !visited[i][j] {
-				return errors.New("map is not connected")
+			ch := row[j]
+			if ch != '#' && !IsPlayerTile(ch) && !visited.Get(i*labyrinth.Cols+j) {
+				return ErrNotConnected
 			}
 		}
 	}
@@ -301,34 +313,67 @@ func readFile(filename string) ([]string, error) {
 	}
 	defer file.Close()
 
+	// 与 LoadMap 一样用 ReadBytes 读取，超长的行不受 bufio.Scanner 的 64KB 限制
 	var lines []string
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		lines = append(lines, scanner.Text())
-	}
-
-	if err := scanner.Err(); err != nil {
-		return nil, err
+	reader := bufio.NewReaderSize(file, 1<<16)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(line) > 0 {
+			line = bytes.TrimSuffix(bytes.TrimSuffix(line, []byte("\n")), []byte("\r"))
+			lines = append(lines, string(line))
+		}
+		if err == io.EOF {
+			return lines, nil
+		}
+		if err != nil {
+			return nil, err
+		}
 	}
-
-	return lines, nil
 }
 
 // 辅助函数：写入文件内容
 func writeFile(filename string, lines []string) error {
-	file, err := os.Create(filename)
+	return writeFileFunc(filename, func(writer *bufio.Writer) error {
+		for _, line := range lines {
+			_, err := writer.WriteString(line + "\n")
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// 辅助函数：流式写入文件内容
+// 先写同目录下的临时文件并 fsync，再 rename 覆盖，崩溃时不会留下写了一半的地图
+func writeFileFunc(filename string, write func(*bufio.Writer) error) error {
+	dir, base := filepath.Split(filename)
+	if dir == "" {
+		dir = "."
+	}
+	file, err := os.CreateTemp(dir, "."+base+".tmp*")
 	if err != nil {
 		return err
 	}
+	tmpName := file.Name()
+	defer os.Remove(tmpName)
 	defer file.Close()
 
-	writer := bufio.NewWriter(file)
-	for _, line := range lines {
-		_, err := writer.WriteString(line + "\n")
-		if err != nil {
-			return err
-		}
+	writer := bufio.NewWriterSize(file, 1<<16)
+	if err := write(writer); err != nil {
+		return err
 	}
-
-	return writer.Flush()
+	if err := writer.Flush(); err != nil {
+		return err
+	}
+	if err := file.Chmod(0644); err != nil {
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmpName, filename)
 }
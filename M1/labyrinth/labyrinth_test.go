@@ -1,7 +1,10 @@
-package main
+package labyrinth
 
 import (
+	"bytes"
+	"errors"
 	"os"
+	"path/filepath"
 	"testing"
 )
 
@@ -32,7 +35,7 @@ func TestIsEmptySpace(t *testing.T) {
 	lab := &Labyrinth{
 		Rows: 3,
 		Cols: 3,
-		Map: [][]rune{
+		Map: [][]byte{
 			{'.', '.', '#'},
 			{'#', '.', '.'},
 			{'.', '.', '.'},
@@ -67,7 +70,7 @@ func TestFindPlayer(t *testing.T) {
 	lab := &Labyrinth{
 		Rows: 3,
 		Cols: 3,
-		Map: [][]rune{
+		Map: [][]byte{
 			{'.', '.', '1'},
 			{'.', '.', '.'},
 			{'.', '.', '.'},
@@ -98,7 +101,7 @@ func TestFindFirstEmptySpace(t *testing.T) {
 	lab := &Labyrinth{
 		Rows: 2,
 		Cols: 2,
-		Map: [][]rune{
+		Map: [][]byte{
 			{'#', '.'},
 			{'#', '#'},
 		},
@@ -119,7 +122,7 @@ func TestIsConnected(t *testing.T) {
 	connected := &Labyrinth{
 		Rows: 3,
 		Cols: 3,
-		Map: [][]rune{
+		Map: [][]byte{
 			{'.', '.', '.'},
 			{'.', '#', '.'},
 			{'.', '.', '.'},
@@ -134,7 +137,7 @@ func TestIsConnected(t *testing.T) {
 	disconnected := &Labyrinth{
 		Rows: 3,
 		Cols: 3,
-		Map: [][]rune{
+		Map: [][]byte{
 			{'.', '.', '#'},
 			{'#', '#', '#'},
 			{'#', '.', '.'},
@@ -192,7 +195,7 @@ func TestMovePlayer(t *testing.T) {
 	lab := &Labyrinth{
 		Rows: 3,
 		Cols: 3,
-		Map: [][]rune{
+		Map: [][]byte{
 			{'.', '0', '.'},
 			{'.', '.', '.'},
 			{'.', '.', '.'},
@@ -224,7 +227,7 @@ func TestDFS(t *testing.T) {
 	lab := &Labyrinth{
 		Rows: 3,
 		Cols: 3,
-		Map: [][]rune{
+		Map: [][]byte{
 			{'.', '.', '#'},
 			{'.', '#', '.'},
 			{'.', '.', '.'},
@@ -254,11 +257,11 @@ func BenchmarkIsConnected(b *testing.B) {
 	lab := &Labyrinth{
 		Rows: 10,
 		Cols: 10,
-		Map:  make([][]rune, 10),
+		Map:  make([][]byte, 10),
 	}
 
 	for i := 0; i < 10; i++ {
-		lab.Map[i] = make([]rune, 10)
+		lab.Map[i] = make([]byte, 10)
 		for j := 0; j < 10; j++ {
 			lab.Map[i][j] = '.'
 		}
@@ -269,3 +272,157 @@ func BenchmarkIsConnected(b *testing.B) {
 		IsConnected(lab)
 	}
 }
+
+// openLabyrinth 生成全是空地的地图，左上角放玩家 0
+func openLabyrinth(rows, cols int) *Labyrinth {
+	lab := &Labyrinth{Rows: rows, Cols: cols, Map: make([][]byte, rows)}
+	for i := range lab.Map {
+		lab.Map[i] = bytes.Repeat([]byte{'.'}, cols)
+	}
+	lab.Map[0][0] = '0'
+	return lab
+}
+
+// BenchmarkIsConnectedLarge 1000x1000 地图的连通性检查
+func BenchmarkIsConnectedLarge(b *testing.B) {
+	lab := openLabyrinth(1000, 1000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := IsConnected(lab); err != nil {
+			b.Fatalf("IsConnected() error: %v", err)
+		}
+	}
+}
+
+// BenchmarkIsConnectedHuge 10000x10000 地图的连通性检查（约 100MB 地图 + 12.5MB 位图）
+func BenchmarkIsConnectedHuge(b *testing.B) {
+	lab := openLabyrinth(MaxRows, MaxCols)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := IsConnected(lab); err != nil {
+			b.Fatalf("IsConnected() error: %v", err)
+		}
+	}
+}
+
+// TestLargeMap 测试超过旧 100x100 限制的地图可以加载、检查连通性并找路
+func TestLargeMap(t *testing.T) {
+	lab := openLabyrinth(300, 400)
+	for i := 1; i < 299; i++ {
+		lab.Map[i][200] = '#'
+	}
+	testFile := filepath.Join(t.TempDir(), "map.txt")
+	if err := SaveMap(lab, testFile); err != nil {
+		t.Fatalf("SaveMap() error: %v", err)
+	}
+	loaded := &Labyrinth{}
+	if err := LoadMap(loaded, testFile); err != nil {
+		t.Fatalf("LoadMap() error: %v", err)
+	}
+	if loaded.Rows != 300 || loaded.Cols != 400 {
+		t.Fatalf("LoadMap() size %dx%d, expected 300x400", loaded.Rows, loaded.Cols)
+	}
+	if err := IsConnected(loaded); err != nil {
+		t.Errorf("IsConnected() error: %v, the wall has gaps at both ends", err)
+	}
+	path, err := AStarPath(loaded, Position{0, 0}, Position{150, 399})
+	if err != nil {
+		t.Fatalf("AStarPath() error: %v", err)
+	}
+	if bfs, _ := ShortestPath(loaded, Position{0, 0}, Position{150, 399}); len(bfs) != len(path) {
+		t.Errorf("AStarPath() length %d, expected %d", len(path), len(bfs))
+	}
+
+	for i := 0; i < 300; i++ {
+		loaded.Map[i][200] = '#'
+	}
+	if err := IsConnected(loaded); err == nil {
+		t.Error("IsConnected() should fail once the wall is closed")
+	}
+}
+
+// TestReadMapVersion 测试只读取版本号
+func TestReadMapVersion(t *testing.T) {
+	testFile := filepath.Join(t.TempDir(), "map.txt")
+	if err := os.WriteFile(testFile, []byte("0..\n...\n@inventory 0 a\n@version 3\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	if version, err := ReadMapVersion(testFile); err != nil || version != 3 {
+		t.Errorf("ReadMapVersion() = %d, %v, expected 3", version, err)
+	}
+	if err := os.WriteFile(testFile, []byte("0..\n...\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	if version, err := ReadMapVersion(testFile); err != nil || version != 0 {
+		t.Errorf("ReadMapVersion() = %d, %v, expected 0", version, err)
+	}
+}
+
+// TestMapVersion 测试版本号的读写
+func TestMapVersion(t *testing.T) {
+	testFile := filepath.Join(t.TempDir(), "map.txt")
+	if err := os.WriteFile(testFile, []byte("0..\n...\n@version 7\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	lab := &Labyrinth{}
+	if err := LoadMap(lab, testFile); err != nil {
+		t.Fatalf("LoadMap() error: %v", err)
+	}
+	if lab.Rows != 2 || lab.Version != 7 {
+		t.Errorf("LoadMap() rows=%d, version=%d, expected rows=2, version=7", lab.Rows, lab.Version)
+	}
+
+	if err := os.WriteFile(testFile, []byte("0..\n...\n@bogus\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	if err := LoadMap(&Labyrinth{}, testFile); err == nil {
+		t.Error("LoadMap() should reject unknown metadata")
+	}
+}
+
+// TestCommitMap 测试提交时检测并发修改
+func TestCommitMap(t *testing.T) {
+	testFile := filepath.Join(t.TempDir(), "map.txt")
+	if err := os.WriteFile(testFile, []byte("0..\n...\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	lock, err := LockMap(testFile)
+	if err != nil {
+		t.Fatalf("LockMap() error: %v", err)
+	}
+	defer lock.Unlock()
+
+	first, second := &Labyrinth{}, &Labyrinth{}
+	if err := LoadMap(first, testFile); err != nil {
+		t.Fatalf("LoadMap() error: %v", err)
+	}
+	if err := LoadMap(second, testFile); err != nil {
+		t.Fatalf("LoadMap() error: %v", err)
+	}
+
+	if err := MovePlayer(first, '0', "right"); err != nil {
+		t.Fatalf("MovePlayer() error: %v", err)
+	}
+	if err := CommitMap(first, testFile); err != nil {
+		t.Fatalf("CommitMap() error: %v", err)
+	}
+	if first.Version != 1 {
+		t.Errorf("CommitMap() version=%d, expected 1", first.Version)
+	}
+
+	// second 基于旧版本，提交应被拒绝
+	if err := MovePlayer(second, '0', "down"); err != nil {
+		t.Fatalf("MovePlayer() error: %v", err)
+	}
+	if err := CommitMap(second, testFile); !errors.Is(err, ErrStaleMap) {
+		t.Errorf("CommitMap() error = %v, expected ErrStaleMap", err)
+	}
+
+	content, err := os.ReadFile(testFile)
+	if err != nil {
+		t.Fatalf("Failed to read saved file: %v", err)
+	}
+	if string(content) != ".0.\n...\n@version 1\n" {
+		t.Errorf("CommitMap() content = %q", content)
+	}
+}
@@ -0,0 +1,44 @@
+//go:build !(darwin || dragonfly || freebsd || linux || netbsd || openbsd)
+
+package labyrinth
+
+import (
+	"errors"
+	"os"
+	"time"
+)
+
+// lockRetry 锁文件已存在时重试的间隔
+const lockRetry = 10 * time.Millisecond
+
+// MapLock 地图文件的锁，没有 flock 的平台上用 O_EXCL 创建的 .lock 文件表示持有锁
+type MapLock struct {
+	path string
+}
+
+// LockMap 获取地图的排他锁，阻塞直到其他进程删除锁文件
+// 锁文件只在 Unlock 时删除，进程异常退出后需要手动删除 map.txt.lock
+func LockMap(filename string) (*MapLock, error) {
+	path := filename + ".lock"
+	for {
+		file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0644)
+		if err == nil {
+			file.Close()
+			return &MapLock{path: path}, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, err
+		}
+		time.Sleep(lockRetry)
+	}
+}
+
+// Unlock 删除锁文件释放锁
+func (l *MapLock) Unlock() error {
+	if l == nil || l.path == "" {
+		return nil
+	}
+	err := os.Remove(l.path)
+	l.path = ""
+	return err
+}
@@ -0,0 +1,41 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+
+package labyrinth
+
+import (
+	"os"
+	"syscall"
+)
+
+// MapLock 地图文件的建议锁
+// 锁加在旁边的 .lock 文件上，因为 SaveMap 会 rename 替换地图文件本身
+type MapLock struct {
+	file *os.File
+}
+
+// LockMap 获取地图的排他锁，阻塞直到其他进程释放
+// 进程退出时内核会自动释放锁，因此 os.Exit 之前不必显式解锁
+func LockMap(filename string) (*MapLock, error) {
+	file, err := os.OpenFile(filename+".lock", os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	if err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return &MapLock{file: file}, nil
+}
+
+// Unlock 释放锁
+func (l *MapLock) Unlock() error {
+	if l == nil || l.file == nil {
+		return nil
+	}
+	err := syscall.Flock(int(l.file.Fd()), syscall.LOCK_UN)
+	if cerr := l.file.Close(); err == nil {
+		err = cerr
+	}
+	l.file = nil
+	return err
+}
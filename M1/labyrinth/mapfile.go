@@ -0,0 +1,170 @@
+package labyrinth
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// MetaPrefix 元数据行前缀，元数据行写在地图之后
+const MetaPrefix = "@"
+
+// ErrStaleMap 地图在读取之后被其他进程修改过
+var ErrStaleMap = errors.New("map changed by another process")
+
+// CommitMap 检查磁盘上的版本仍是加载时的版本，然后版本号加一并保存
+// 调用方应持有 LockMap 返回的锁；不遵守锁的写入者会被版本号发现
+func CommitMap(labyrinth *Labyrinth, filename string) error {
+	version, err := ReadMapVersion(filename)
+	if err != nil {
+		return err
+	}
+	if version != labyrinth.Version {
+		return ErrStaleMap
+	}
+	labyrinth.Version++
+	if err := SaveMap(labyrinth, filename); err != nil {
+		labyrinth.Version--
+		return err
+	}
+	return nil
+}
+
+// ReadMapVersion 只读取地图文件中的 @version，不构建地图，大地图提交时避免整份重新加载
+func ReadMapVersion(filename string) (int, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+	reader := bufio.NewReaderSize(file, 1<<16)
+	prefix := []byte(MetaPrefix + "version ")
+	atLineStart := true
+	for {
+		// ReadSlice 复用缓冲区，超长的行分多次返回 bufio.ErrBufferFull
+		line, err := reader.ReadSlice('\n')
+		if atLineStart && bytes.HasPrefix(line, prefix) {
+			value := strings.TrimSpace(string(line[len(prefix):]))
+			version, convErr := strconv.Atoi(value)
+			if convErr != nil || version < 0 {
+				return 0, fmt.Errorf("invalid version %q", value)
+			}
+			return version, nil
+		}
+		atLineStart = err != bufio.ErrBufferFull
+		if err == io.EOF {
+			return 0, nil
+		}
+		if err != nil && err != bufio.ErrBufferFull {
+			return 0, err
+		}
+	}
+}
+
+// splitMeta 把文件内容分成地图行与元数据行
+func splitMeta(lines []string) ([]string, []string) {
+	for i, line := range lines {
+		if strings.HasPrefix(line, MetaPrefix) {
+			return lines[:i], lines[i:]
+		}
+	}
+	return lines, nil
+}
+
+// parseMeta 解析 "@key value" 形式的元数据行
+func parseMeta(labyrinth *Labyrinth, meta []string) error {
+	for _, line := range meta {
+		key, value, _ := strings.Cut(strings.TrimPrefix(line, MetaPrefix), " ")
+		switch key {
+		case "version":
+			version, err := strconv.Atoi(strings.TrimSpace(value))
+			if err != nil || version < 0 {
+				return fmt.Errorf("invalid version %q", value)
+			}
+			labyrinth.Version = version
+		case "inventory", "under", "finished":
+			if err := parsePlayerMeta(labyrinth, key, value); err != nil {
+				return err
+			}
+		case "rules", "turn", "moves", "score", "captured", "over":
+			if err := parseGameMeta(labyrinth, key, value); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("unknown metadata %q", line)
+		}
+	}
+	return nil
+}
+
+// formatMeta 生成元数据行；没有元数据时保持原始地图格式不变
+func formatMeta(labyrinth *Labyrinth) []string {
+	var meta []string
+	if labyrinth.Version > 0 {
+		meta = append(meta, fmt.Sprintf("%sversion %d", MetaPrefix, labyrinth.Version))
+	}
+	for _, id := range sortedPlayers(labyrinth.Inventory) {
+		if keys := labyrinth.Inventory[id]; keys != "" {
+			meta = append(meta, fmt.Sprintf("%sinventory %c %s", MetaPrefix, id, keys))
+		}
+	}
+	for _, id := range sortedPlayers(labyrinth.Under) {
+		meta = append(meta, fmt.Sprintf("%sunder %c %c", MetaPrefix, id, labyrinth.Under[id]))
+	}
+	for _, id := range sortedPlayers(labyrinth.Finished) {
+		if labyrinth.Finished[id] {
+			meta = append(meta, fmt.Sprintf("%sfinished %c", MetaPrefix, id))
+		}
+	}
+	meta = append(meta, formatGameMeta(labyrinth)...)
+	return meta
+}
+
+// parsePlayerMeta 解析按玩家记录的元数据：
+//
+//	@inventory ID KEYS
+//	@under ID TILE
+//	@finished ID
+func parsePlayerMeta(labyrinth *Labyrinth, key, value string) error {
+	fields := strings.Fields(value)
+	if len(fields) == 0 || len(fields[0]) != 1 || !IsValidPlayer(fields[0]) {
+		return fmt.Errorf("invalid %s %q", key, value)
+	}
+	id := byte(fields[0][0])
+	switch {
+	case key == "inventory" && len(fields) == 2:
+		for _, k := range []byte(fields[1]) {
+			if !IsKey(k) {
+				return fmt.Errorf("invalid %s %q", key, value)
+			}
+		}
+		labyrinth.Inventory[id] = fields[1]
+	case key == "under" && len(fields) == 2 && len([]byte(fields[1])) == 1:
+		tile := []byte(fields[1])[0]
+		if !IsTeleporter(tile) && !IsStairs(tile) && tile != ExitTile {
+			return fmt.Errorf("invalid %s %q", key, value)
+		}
+		labyrinth.Under[id] = tile
+	case key == "finished" && len(fields) == 1:
+		labyrinth.Finished[id] = true
+	default:
+		return fmt.Errorf("invalid %s %q", key, value)
+	}
+	return nil
+}
+
+// sortedPlayers 按玩家 ID 排序，保证保存结果稳定
+func sortedPlayers[V any](m map[byte]V) []byte {
+	ids := make([]byte, 0, len(m))
+	for id := range m {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
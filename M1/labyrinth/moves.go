@@ -0,0 +1,206 @@
+package labyrinth
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"strings"
+)
+
+// RunPrefix 批量移动中 run:DIR 表示一直朝 DIR 走到走不动为止
+const RunPrefix = "run:"
+
+// ParseMoves 解析批量移动："up,up,left,run:right"，逗号、空白与换行都可以分隔
+// 以 # 开头的行是注释，便于在 --moves-file 中书写
+func ParseMoves(s string) ([]string, error) {
+	var moves []string
+	for _, line := range strings.Split(s, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "#") {
+			continue
+		}
+		fields := strings.FieldsFunc(line, func(r rune) bool {
+			return r == ',' || r == ' ' || r == '\t' || r == '\r'
+		})
+		for _, move := range fields {
+			if !isDirection(strings.TrimPrefix(move, RunPrefix)) {
+				return nil, fmt.Errorf("invalid move %q", move)
+			}
+			moves = append(moves, move)
+		}
+	}
+	if len(moves) == 0 {
+		return nil, errors.New("no moves")
+	}
+	return moves, nil
+}
+
+// ReadMovesFile 从文件读取批量移动
+func ReadMovesFile(filename string) ([]string, error) {
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return ParseMoves(string(content))
+}
+
+func isDirection(name string) bool {
+	if name == DirectionUpstairs || name == DirectionDownstairs {
+		return true
+	}
+	for _, d := range Directions {
+		if d.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// PlayMoves 按顺序执行批量移动，返回每一步的事件
+// 批量是原子的：任何一步失败时按事件中记录的附带修改倒序撤回，labyrinth 保持调用前的状态
+func PlayMoves(labyrinth *Labyrinth, playerID byte, moves []string) ([]MoveEvent, error) {
+	var events []MoveEvent
+	for i, move := range moves {
+		direction, run := strings.CutPrefix(move, RunPrefix)
+		event, err := playStep(labyrinth, playerID, direction)
+		if err != nil {
+			err = fmt.Errorf("move %d (%s): %w", i+1, move, err)
+			if undoErr := undoEvents(labyrinth, events); undoErr != nil {
+				return nil, fmt.Errorf("%w, undo moves: %v", err, undoErr)
+			}
+			return nil, err
+		}
+		events = append(events, event)
+		if !run {
+			continue
+		}
+		// 走到被挡住、到达出口或回到走过的位置（传送门成环）为止
+		seen := map[Position]bool{event.From: true, event.To: true}
+		for !event.Finished {
+			next, err := playStep(labyrinth, playerID, direction)
+			if err != nil {
+				break
+			}
+			events = append(events, next)
+			if seen[next.To] {
+				break
+			}
+			seen[next.To] = true
+			event = next
+		}
+	}
+	return events, nil
+}
+
+// undoEvents 倒序撤回已经执行的移动
+func undoEvents(labyrinth *Labyrinth, events []MoveEvent) error {
+	for i := len(events) - 1; i >= 0; i-- {
+		if err := undoEntry(labyrinth, journalEntry(events[i])); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// playStep 在规则约束下走一步并生成事件，事件中记录撤销所需的附带修改
+func playStep(labyrinth *Labyrinth, playerID byte, direction string) (MoveEvent, error) {
+	from, err := FindPlayer(labyrinth, playerID)
+	if err != nil {
+		return MoveEvent{}, err
+	}
+	before := snapshotEffects(labyrinth, playerID, *from)
+	if err := PlayMove(labyrinth, playerID, direction); err != nil {
+		return MoveEvent{}, err
+	}
+	event := MoveEvent{Player: playerID, Direction: direction, From: *from, To: *from}
+	if to, err := FindPlayer(labyrinth, playerID); err == nil {
+		event.To = *to
+	}
+	event.Finished = labyrinth.Finished[playerID]
+	event.Effects = before.diff(labyrinth, event.To)
+	return event, nil
+}
+
+// effectsSnapshot 移动前与撤销有关的状态，只保存玩家四周的地块而不是整张地图
+type effectsSnapshot struct {
+	playerID  byte
+	around    map[Position]byte
+	inventory string
+	finished  bool
+
+	rules    bool // 以下为规则状态，没有规则时无效
+	turn     byte
+	over     bool
+	captured map[byte]bool
+	hadScore bool
+	score    int
+}
+
+func snapshotEffects(labyrinth *Labyrinth, playerID byte, from Position) effectsSnapshot {
+	s := effectsSnapshot{
+		playerID:  playerID,
+		around:    make(map[Position]byte, len(Directions)),
+		inventory: labyrinth.Inventory[playerID],
+		finished:  labyrinth.Finished[playerID],
+	}
+	for _, d := range Directions {
+		if p := (Position{from.Row + d.DRow, from.Col + d.DCol}); inBounds(labyrinth, p) {
+			s.around[p] = labyrinth.Map[p.Row][p.Col]
+		}
+	}
+	if game := labyrinth.Game; game != nil {
+		s.rules, s.turn, s.over = true, game.Turn, game.Over
+		s.captured = maps.Clone(game.Captured)
+		s.score, s.hadScore = game.Scores[playerID]
+	}
+	return s
+}
+
+// diff 移动成功后与快照比较得到附带修改，to 为玩家的落点
+func (s effectsSnapshot) diff(labyrinth *Labyrinth, to Position) MoveEffects {
+	var e MoveEffects
+	if tile := s.around[to]; IsKey(tile) || IsDoor(tile) {
+		e.Tile = tile
+		if IsKey(tile) && strings.IndexByte(s.inventory, tile) < 0 {
+			e.Key = tile
+		}
+	}
+	e.Exit = !s.finished && labyrinth.Finished[s.playerID]
+	game := labyrinth.Game
+	if !s.rules || game == nil {
+		return e
+	}
+	for p, tile := range s.around {
+		if IsPlayerTile(tile) && game.Captured[tile] && !s.captured[tile] {
+			e.Victim, e.VictimAt = tile, p
+		}
+	}
+	e.Counted = true
+	e.NewScore = !s.hadScore
+	e.Score = game.Scores[s.playerID] - s.score
+	e.Turn, e.NextTurn = s.turn, game.Turn
+	if game.Over && !s.over {
+		e.Over, e.Winner = true, game.Winner
+	}
+	return e
+}
+
+// Clone 深拷贝地图与所有状态
+func Clone(labyrinth *Labyrinth) *Labyrinth {
+	clone := *labyrinth
+	clone.Map = make([][]byte, len(labyrinth.Map))
+	for i, row := range labyrinth.Map {
+		clone.Map[i] = append([]byte(nil), row...)
+	}
+	clone.Inventory = maps.Clone(labyrinth.Inventory)
+	clone.Under = maps.Clone(labyrinth.Under)
+	clone.Finished = maps.Clone(labyrinth.Finished)
+	if labyrinth.Game != nil {
+		game := *labyrinth.Game
+		game.Moves = maps.Clone(game.Moves)
+		game.Scores = maps.Clone(game.Scores)
+		game.Captured = maps.Clone(game.Captured)
+		clone.Game = &game
+	}
+	return &clone
+}
@@ -0,0 +1,80 @@
+package labyrinth
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestParseMoves 测试批量移动的解析
+func TestParseMoves(t *testing.T) {
+	moves, err := ParseMoves("up,up, left\n# comment\nrun:right downstairs")
+	if err != nil {
+		t.Fatalf("ParseMoves() error: %v", err)
+	}
+	want := []string{"up", "up", "left", "run:right", "downstairs"}
+	if !reflect.DeepEqual(moves, want) {
+		t.Errorf("ParseMoves() = %v, expected %v", moves, want)
+	}
+	for _, bad := range []string{"", "up,jump", "run:", "run:run:up"} {
+		if _, err := ParseMoves(bad); err == nil {
+			t.Errorf("ParseMoves(%q) should fail", bad)
+		}
+	}
+}
+
+// TestPlayMoves 测试 run: 与每一步的位置
+func TestPlayMoves(t *testing.T) {
+	lab := newTestLabyrinth(
+		"0...#",
+		"#.#..",
+	)
+	events, err := PlayMoves(lab, '0', []string{"run:right", "left", "left", "down"})
+	if err != nil {
+		t.Fatalf("PlayMoves() error: %v", err)
+	}
+	var got []Position
+	for _, e := range events {
+		got = append(got, e.To)
+	}
+	want := []Position{{0, 1}, {0, 2}, {0, 3}, {0, 2}, {0, 1}, {1, 1}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("PlayMoves() positions = %v, expected %v", got, want)
+	}
+	if lab.Map[1][1] != '0' {
+		t.Errorf("PlayMoves() did not apply the moves:\n%s", lab.Map)
+	}
+}
+
+// TestPlayMovesAtomic 测试任何一步失败时地图保持不变
+func TestPlayMovesAtomic(t *testing.T) {
+	lab := newTestLabyrinth(
+		"0.a",
+		"##A",
+	)
+	lab.Inventory = map[byte]string{}
+	if _, err := PlayMoves(lab, '0', []string{"right", "right", "up"}); err == nil {
+		t.Fatal("PlayMoves() should fail on the last move")
+	}
+	if string(lab.Map[0]) != "0.a" || lab.Inventory['0'] != "" {
+		t.Errorf("PlayMoves() changed the map after failing: %q, keys %q", lab.Map[0], lab.Inventory['0'])
+	}
+
+	// 规则状态（步数、分数、轮次）与出口同样撤回
+	lab = newTestLabyrinth(
+		"0.E",
+		"1.#",
+	)
+	lab.Game = NewGameState(Rules{Mode: ModeRace, TurnOrder: true})
+	if _, err := PlayMoves(lab, '0', []string{"right", "right"}); err == nil {
+		t.Fatal("PlayMoves() should fail when the turn passes to another player")
+	}
+	if string(lab.Map[0]) != "0.E" || len(lab.Game.Moves) != 0 || len(lab.Game.Scores) != 0 || lab.Game.Turn != 0 {
+		t.Errorf("PlayMoves() left rules state after failing: %q, moves %v, scores %v, turn %q",
+			lab.Map[0], lab.Game.Moves, lab.Game.Scores, lab.Game.Turn)
+	}
+
+	// run: 一步也走不动时视为非法移动
+	if _, err := PlayMoves(lab, '0', []string{"run:up"}); err == nil {
+		t.Error("PlayMoves(run:up) should fail when blocked immediately")
+	}
+}
@@ -0,0 +1,398 @@
+package labyrinth
+
+import (
+	"container/heap"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// AStarThreshold 地图格子数超过该值时命令行改用 A*
+const AStarThreshold = 2500
+
+// maxPathStates 跟踪钥匙时最多展开的状态数，状态数随钥匙数指数增长，超过时返回 ErrPathTooComplex
+var maxPathStates = 1 << 24
+
+// ErrPathTooComplex 钥匙与门太多，寻路状态超过 maxPathStates
+var ErrPathTooComplex = errors.New("too many key combinations to search")
+
+// Direction 移动方向及其行列偏移
+type Direction struct {
+	Name string
+	DRow int
+	DCol int
+}
+
+// Directions 与 MovePlayer 支持的方向一致
+var Directions = []Direction{
+	{"up", -1, 0},
+	{"down", 1, 0},
+	{"left", 0, -1},
+	{"right", 0, 1},
+}
+
+// PathStep 路径中的一步：移动方向与移动后的位置（经过传送门时为传送门另一端）
+type PathStep struct {
+	Direction string
+	To        Position
+}
+
+// ShortestPath 使用 BFS 计算从 from 到 to 的最短路径，每一步都可以直接交给 MovePlayer
+// 返回值不包含起点，最后一步到达 to；其他玩家视为障碍（与 IsEmptySpace 一致）
+// from 上有玩家时按该玩家的背包判断门，路上拾取的钥匙可以打开之后的门
+func ShortestPath(labyrinth *Labyrinth, from, to Position) ([]PathStep, error) {
+	if err := checkEndpoints(labyrinth, from, to); err != nil {
+		return nil, err
+	}
+	if from == to {
+		return []PathStep{}, nil
+	}
+	search := newPathSearch(labyrinth, from)
+	// 没有需要跟踪的钥匙时状态就是格子编号，前驱按编号平铺存储，10000x10000 地图约 400MB
+	var prev pathPrev = mapPrev{}
+	if len(search.keyBits) == 0 {
+		prev = newFlatPrev(labyrinth.Rows * labyrinth.Cols)
+	}
+	start := search.state(from, 0)
+	prev.set(start, start, 0)
+	states := 1
+	queue := []int64{start}
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		for move := range pathMoves {
+			next, ok := search.move(cur, move)
+			if !ok {
+				continue
+			}
+			if _, _, seen := prev.get(next); seen {
+				continue
+			}
+			if states++; search.tooComplex(states) {
+				return nil, ErrPathTooComplex
+			}
+			prev.set(next, cur, move)
+			if search.position(next) == to {
+				return search.build(prev, start, next), nil
+			}
+			queue = append(queue, next)
+		}
+	}
+	return nil, errors.New("no path")
+}
+
+// AStarPath 与 ShortestPath 结果长度相同，使用曼哈顿距离作为启发函数，适合大地图
+func AStarPath(labyrinth *Labyrinth, from, to Position) ([]PathStep, error) {
+	if err := checkEndpoints(labyrinth, from, to); err != nil {
+		return nil, err
+	}
+	if from == to {
+		return []PathStep{}, nil
+	}
+	search := newPathSearch(labyrinth, from)
+	// 只记录展开过的状态，内存与搜索范围成正比而不是与地图大小成正比
+	start := search.state(from, 0)
+	prev := mapPrev{}
+	prev.set(start, start, 0)
+	cost := map[int64]int{start: 0}
+	open := &positionHeap{}
+	heap.Push(open, heapItem{start, search.estimate(from, to)})
+	for open.Len() > 0 {
+		cur := heap.Pop(open).(heapItem).state
+		if search.position(cur) == to {
+			return search.build(prev, start, cur), nil
+		}
+		for move := range pathMoves {
+			next, ok := search.move(cur, move)
+			if !ok {
+				continue
+			}
+			g := cost[cur] + 1
+			if c, ok := cost[next]; ok && c <= g {
+				continue
+			}
+			cost[next] = g
+			if search.tooComplex(len(cost)) {
+				return nil, ErrPathTooComplex
+			}
+			prev.set(next, cur, move)
+			heap.Push(open, heapItem{next, g + search.estimate(search.position(next), to)})
+		}
+	}
+	return nil, errors.New("no path")
+}
+
+// PathDirections 路径中每一步的方向
+func PathDirections(path []PathStep) []string {
+	moves := make([]string, len(path))
+	for i, step := range path {
+		moves[i] = step.Direction
+	}
+	return moves
+}
+
+// pathMoves 寻路时尝试的移动，下标记录在前驱中
+var pathMoves = []string{"up", "down", "left", "right", DirectionUpstairs, DirectionDownstairs}
+
+// pathSearch 按 MovePlayer 的规则展开寻路状态
+// 状态编号为 钥匙集合*格子数+格子编号，钥匙集合只包含地图上有对应门、玩家还没有的钥匙
+type pathSearch struct {
+	labyrinth   *Labyrinth
+	from        Position
+	player      byte // from 上的玩家，没有时为 0
+	keyBits     map[byte]int64
+	teleporters map[byte][]Position
+}
+
+func newPathSearch(labyrinth *Labyrinth, from Position) *pathSearch {
+	search := &pathSearch{
+		labyrinth:   labyrinth,
+		from:        from,
+		keyBits:     make(map[byte]int64),
+		teleporters: TeleporterPositions(labyrinth),
+	}
+	if tile := labyrinth.Map[from.Row][from.Col]; IsPlayerTile(tile) {
+		search.player = tile
+	}
+	doors, keys := make(map[byte]bool), make(map[byte]bool)
+	for _, row := range labyrinth.Map {
+		for _, ch := range row {
+			if IsDoor(ch) {
+				doors[DoorKey(ch)] = true
+			} else if IsKey(ch) {
+				keys[ch] = true
+			}
+		}
+	}
+	for key := byte('a'); key <= 'z'; key++ {
+		if doors[key] && keys[key] && !search.hasKey(key, 0) {
+			search.keyBits[key] = 1 << len(search.keyBits)
+		}
+	}
+	return search
+}
+
+// tooComplex 跟踪钥匙时状态数是否超过 maxPathStates；不跟踪钥匙时状态数不超过格子数
+func (s *pathSearch) tooComplex(states int) bool {
+	return len(s.keyBits) > 0 && states > maxPathStates
+}
+
+func (s *pathSearch) state(p Position, keys int64) int64 {
+	return keys*int64(s.labyrinth.Rows*s.labyrinth.Cols) + int64(cellIndex(s.labyrinth, p))
+}
+
+func (s *pathSearch) position(state int64) Position {
+	i := int(state % int64(s.labyrinth.Rows*s.labyrinth.Cols))
+	return Position{i / s.labyrinth.Cols, i % s.labyrinth.Cols}
+}
+
+func (s *pathSearch) keys(state int64) int64 {
+	return state / int64(s.labyrinth.Rows*s.labyrinth.Cols)
+}
+
+func (s *pathSearch) hasKey(key byte, keys int64) bool {
+	return (s.player != 0 && HasKey(s.labyrinth, s.player, key)) || keys&s.keyBits[key] != 0
+}
+
+// tile 位置 p 上的地块；起点上的玩家离开后露出脚下的地块，其他玩家是障碍
+func (s *pathSearch) tile(p Position) byte {
+	if p.Row < 0 || p.Row >= s.labyrinth.Rows || p.Col < 0 || p.Col >= s.labyrinth.Cols {
+		return '#'
+	}
+	if p == s.from && s.player != 0 {
+		return TileUnder(s.labyrinth, s.player)
+	}
+	if tile := s.labyrinth.Map[p.Row][p.Col]; !IsPlayerTile(tile) {
+		return tile
+	}
+	return '#'
+}
+
+// move 从 state 执行 pathMoves[move] 后的状态，与 enterTile 的规则一致
+func (s *pathSearch) move(state int64, move int) (int64, bool) {
+	p, keys := s.position(state), s.keys(state)
+	tile := s.tile(p)
+	if tile == ExitTile {
+		return 0, false
+	}
+	var target Position
+	if move < len(Directions) {
+		d := Directions[move]
+		target = Position{p.Row + d.DRow, p.Col + d.DCol}
+	} else {
+		link, direction, ok := StairLink(s.labyrinth, p, tile)
+		if !ok || direction != pathMoves[move] {
+			return 0, false
+		}
+		target = link
+	}
+	tile = s.tile(target)
+	switch {
+	case tile == '.' || IsStairs(tile) || tile == ExitTile:
+	case IsKey(tile):
+		keys |= s.keyBits[tile]
+	case IsDoor(tile):
+		if !s.hasKey(DoorKey(tile), keys) {
+			return 0, false
+		}
+	case IsTeleporter(tile):
+		landed := false
+		for _, other := range s.teleporters[tile] {
+			if other != target && s.tile(other) == tile {
+				target, landed = other, true
+			}
+		}
+		if !landed {
+			return 0, false
+		}
+	default:
+		return 0, false
+	}
+	return s.state(target, keys), true
+}
+
+// estimate A* 的启发函数：层内曼哈顿距离加上层数差，每次上下楼只走一步，不会高估
+// 有传送门时路径可能从最近的传送门进、从离终点最近的传送门出，取两者中较小的
+func (s *pathSearch) estimate(a, b Position) int {
+	h := estimate(s.labyrinth, a, b)
+	if len(s.teleporters) == 0 {
+		return h
+	}
+	toTeleporter, fromTeleporter := h, h
+	for _, positions := range s.teleporters {
+		for _, t := range positions {
+			toTeleporter = min(toTeleporter, estimate(s.labyrinth, a, t))
+			fromTeleporter = min(fromTeleporter, estimate(s.labyrinth, t, b))
+		}
+	}
+	return min(h, toTeleporter+fromTeleporter)
+}
+
+// build 沿前驱从 end 回溯到 start
+func (s *pathSearch) build(prev pathPrev, start, end int64) []PathStep {
+	var path []PathStep
+	for cur := end; cur != start; {
+		before, move, _ := prev.get(cur)
+		path = append(path, PathStep{Direction: pathMoves[move], To: s.position(cur)})
+		cur = before
+	}
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+	return path
+}
+
+// pathPrev 每个状态的前驱状态与走过来的移动
+type pathPrev interface {
+	get(state int64) (prev int64, move int, ok bool)
+	set(state, prev int64, move int)
+}
+
+// flatPrev 状态就是格子编号时的平铺存储，每项为 前驱*8+移动，-1 表示没有到达
+type flatPrev []int32
+
+func newFlatPrev(n int) flatPrev {
+	prev := make(flatPrev, n)
+	for i := range prev {
+		prev[i] = -1
+	}
+	return prev
+}
+
+func (f flatPrev) get(state int64) (int64, int, bool) {
+	v := f[state]
+	return int64(v / 8), int(v % 8), v >= 0
+}
+
+func (f flatPrev) set(state, prev int64, move int) {
+	f[state] = int32(prev*8) + int32(move)
+}
+
+// mapPrev 只记录到达过的状态，每项为 前驱*8+移动
+type mapPrev map[int64]int64
+
+func (m mapPrev) get(state int64) (int64, int, bool) {
+	v, ok := m[state]
+	return v / 8, int(v % 8), ok
+}
+
+func (m mapPrev) set(state, prev int64, move int) {
+	m[state] = prev*8 + int64(move)
+}
+
+// neighbors 一步可以到达的位置：上下左右，站在楼梯上时还有楼梯另一端
+// 结果追加到 buf 后返回，调用方可以传入栈上的数组避免分配
+func neighbors(labyrinth *Labyrinth, p Position, buf []Position) []Position {
+	for _, d := range Directions {
+		buf = append(buf, Position{p.Row + d.DRow, p.Col + d.DCol})
+	}
+	tile := labyrinth.Map[p.Row][p.Col]
+	if IsPlayerTile(tile) {
+		tile = TileUnder(labyrinth, tile)
+	}
+	if link, _, ok := StairLink(labyrinth, p, tile); ok {
+		buf = append(buf, link)
+	}
+	return buf
+}
+
+// ParsePosition 解析 "ROW,COL"
+func ParsePosition(s string) (Position, error) {
+	parts := strings.Split(s, ",")
+	if len(parts) != 2 {
+		return Position{}, fmt.Errorf("invalid position %q", s)
+	}
+	row, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+	if err != nil {
+		return Position{}, fmt.Errorf("invalid position %q", s)
+	}
+	col, err := strconv.Atoi(strings.TrimSpace(parts[1]))
+	if err != nil {
+		return Position{}, fmt.Errorf("invalid position %q", s)
+	}
+	return Position{row, col}, nil
+}
+
+func checkEndpoints(labyrinth *Labyrinth, from, to Position) error {
+	if from.Row < 0 || from.Row >= labyrinth.Rows || from.Col < 0 || from.Col >= labyrinth.Cols {
+		return errors.New("start out of bounds")
+	}
+	if from != to && !IsEmptySpace(labyrinth, to.Row, to.Col) {
+		return errors.New("target is not an empty space")
+	}
+	return nil
+}
+
+// estimate A* 的启发函数：层内曼哈顿距离加上层数差，每次上下楼只走一步，不会高估
+func estimate(labyrinth *Labyrinth, a, b Position) int {
+	floorA, rowA := FloorOf(labyrinth, a.Row)
+	floorB, rowB := FloorOf(labyrinth, b.Row)
+	return abs(floorA-floorB) + abs(rowA-rowB) + abs(a.Col-b.Col)
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+type heapItem struct {
+	state    int64
+	priority int
+}
+
+// positionHeap A* 的开放列表（小顶堆）
+type positionHeap []heapItem
+
+func (h positionHeap) Len() int           { return len(h) }
+func (h positionHeap) Less(i, j int) bool { return h[i].priority < h[j].priority }
+func (h positionHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *positionHeap) Push(x any)        { *h = append(*h, x.(heapItem)) }
+func (h *positionHeap) Pop() any {
+	old := *h
+	item := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return item
+}
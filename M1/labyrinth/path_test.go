@@ -0,0 +1,169 @@
+package labyrinth
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func newTestLabyrinth(rows ...string) *Labyrinth {
+	lab := &Labyrinth{Rows: len(rows), Cols: len(rows[0])}
+	for _, row := range rows {
+		lab.Map = append(lab.Map, []byte(row))
+	}
+	return lab
+}
+
+// TestShortestPath 测试 BFS 最短路径
+func TestShortestPath(t *testing.T) {
+	lab := newTestLabyrinth(
+		"0.#..",
+		".##.#",
+		".....",
+	)
+	from := Position{0, 0}
+	path, err := ShortestPath(lab, from, Position{0, 3})
+	if err != nil {
+		t.Fatalf("ShortestPath() error: %v", err)
+	}
+	got := strings.Join(PathDirections(path), ",")
+	if got != "down,down,right,right,right,up,up" {
+		t.Errorf("ShortestPath() = %s", got)
+	}
+
+	if _, err := ShortestPath(lab, from, Position{0, 2}); err == nil {
+		t.Error("ShortestPath() to a wall should fail")
+	}
+}
+
+// TestShortestPathPlayersBlock 测试其他玩家视为障碍
+func TestShortestPathPlayersBlock(t *testing.T) {
+	lab := newTestLabyrinth(
+		"0.1.",
+		"###.",
+	)
+	if _, err := ShortestPath(lab, Position{0, 0}, Position{1, 3}); err == nil {
+		t.Error("ShortestPath() should not pass through another player")
+	}
+}
+
+// TestAStarPath 测试 A* 与 BFS 路径长度一致
+func TestAStarPath(t *testing.T) {
+	lab := newTestLabyrinth(
+		"0.........",
+		"########..",
+		"..........",
+		".#########",
+		"..........",
+	)
+	from, to := Position{0, 0}, Position{4, 9}
+	bfs, err := ShortestPath(lab, from, to)
+	if err != nil {
+		t.Fatalf("ShortestPath() error: %v", err)
+	}
+	astar, err := AStarPath(lab, from, to)
+	if err != nil {
+		t.Fatalf("AStarPath() error: %v", err)
+	}
+	if len(bfs) != len(astar) {
+		t.Errorf("AStarPath() length %d, expected %d", len(astar), len(bfs))
+	}
+	if astar[len(astar)-1].To != to {
+		t.Errorf("AStarPath() ends at %v, expected %v", astar[len(astar)-1], to)
+	}
+}
+
+// TestPathReplay 测试返回的路径可以原样交给 PlayMoves：传送门、门与路上拾取的钥匙
+func TestPathReplay(t *testing.T) {
+	tests := []struct {
+		name      string
+		rows      []string
+		inventory string
+		to        Position
+		moves     string
+	}{
+		{"teleporter", []string{"0*..*"}, "", Position{0, 3}, "right,left"},
+		{"key on the way", []string{"0.A.", "a###"}, "", Position{0, 3}, "down,up,right,right,right"},
+		{"key in inventory", []string{"0.A.", "a###"}, "a", Position{0, 3}, "right,right,right"},
+		{"teleporter into a closed room", []string{"0*A..", "####*", "a...."}, "", Position{2, 1}, "right,down,left,left,left"},
+	}
+	for _, tt := range tests {
+		for name, find := range map[string]func(*Labyrinth, Position, Position) ([]PathStep, error){
+			"ShortestPath": ShortestPath,
+			"AStarPath":    AStarPath,
+		} {
+			lab := newTestLabyrinth(tt.rows...)
+			if tt.inventory != "" {
+				lab.Inventory = map[byte]string{'0': tt.inventory}
+			}
+			path, err := find(lab, Position{0, 0}, tt.to)
+			if err != nil {
+				t.Errorf("%s %s: error %v", tt.name, name, err)
+				continue
+			}
+			moves := PathDirections(path)
+			if name == "ShortestPath" && strings.Join(moves, ",") != tt.moves {
+				t.Errorf("%s %s = %s, expected %s", tt.name, name, strings.Join(moves, ","), tt.moves)
+			}
+			events, err := PlayMoves(lab, '0', moves)
+			if err != nil {
+				t.Errorf("%s %s: PlayMoves(%v) error: %v", tt.name, name, moves, err)
+				continue
+			}
+			for i, event := range events {
+				if event.To != path[i].To {
+					t.Errorf("%s %s: step %d at %v, path says %v", tt.name, name, i+1, event.To, path[i].To)
+				}
+			}
+			if last := events[len(events)-1].To; last != tt.to {
+				t.Errorf("%s %s: replay ends at %v, expected %v", tt.name, name, last, tt.to)
+			}
+		}
+	}
+
+	// 没有钥匙时门挡路
+	lab := newTestLabyrinth("0A.")
+	if _, err := ShortestPath(lab, Position{0, 0}, Position{0, 2}); err == nil {
+		t.Error("ShortestPath() should not pass a locked door")
+	}
+}
+
+// TestPathTooComplex 测试钥匙组合太多时寻路返回错误而不是耗尽内存
+func TestPathTooComplex(t *testing.T) {
+	defer func(limit int) { maxPathStates = limit }(maxPathStates)
+	maxPathStates = 1000
+	// 8 把钥匙可以按任意顺序拾取，各有一扇门，终点被墙围住，搜索会展开所有钥匙组合
+	lab := newTestLabyrinth(
+		"0.a.b.c.d.",
+		"..........",
+		"e.f.g.h...",
+		"ABCDEFGH#.",
+		".......#.#",
+	)
+	for name, search := range map[string]func(*Labyrinth, Position, Position) ([]PathStep, error){
+		"ShortestPath": ShortestPath,
+		"AStarPath":    AStarPath,
+	} {
+		if _, err := search(lab, Position{0, 0}, Position{4, 8}); !errors.Is(err, ErrPathTooComplex) {
+			t.Errorf("%s() error = %v, expected ErrPathTooComplex", name, err)
+		}
+	}
+	// 不跟踪钥匙时不受限制
+	lab = newTestLabyrinth(strings.Repeat(".", 40), "0"+strings.Repeat(".", 39))
+	maxPathStates = 10
+	if _, err := ShortestPath(lab, Position{1, 0}, Position{0, 39}); err != nil {
+		t.Errorf("ShortestPath() without keys error: %v", err)
+	}
+}
+
+// TestParsePosition 测试坐标解析
+func TestParsePosition(t *testing.T) {
+	if p, err := ParsePosition("3,4"); err != nil || p != (Position{3, 4}) {
+		t.Errorf("ParsePosition(3,4) = %v, %v", p, err)
+	}
+	for _, s := range []string{"", "3", "a,b", "1,2,3"} {
+		if _, err := ParsePosition(s); err == nil {
+			t.Errorf("ParsePosition(%q) should fail", s)
+		}
+	}
+}
@@ -0,0 +1,363 @@
+package labyrinth
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// 游戏模式
+const (
+	ModeFree = "free" // 没有胜负，只记录到达出口
+	ModeRace = "race" // 第一个到达出口的玩家获胜
+	ModeTag  = "tag"  // 走进其他玩家的格子即抓住对方，最后剩下的玩家获胜
+)
+
+// 计分
+const (
+	ExitScore    = 10
+	CaptureScore = 1
+)
+
+// Rules 规则配置
+type Rules struct {
+	Mode      string
+	TurnOrder bool // 按玩家 ID 顺序轮流移动
+	MoveLimit int  // 每个玩家的最大移动次数，0 表示不限
+}
+
+// GameState 规则层的状态，保存在地图文件的元数据中
+type GameState struct {
+	Rules    Rules
+	Turn     byte // 当前轮到的玩家，仅在 TurnOrder 时有效
+	Moves    map[byte]int
+	Scores   map[byte]int
+	Captured map[byte]bool
+	Over     bool
+	Winner   byte // 0 表示没有胜者（平局）
+}
+
+// NewGameState 创建规则状态
+func NewGameState(rules Rules) *GameState {
+	return &GameState{
+		Rules:    rules,
+		Moves:    make(map[byte]int),
+		Scores:   make(map[byte]int),
+		Captured: make(map[byte]bool),
+	}
+}
+
+// ActivePlayers 仍在游戏中的玩家（在地图上、未被抓、未到达出口），按 ID 排序
+func ActivePlayers(labyrinth *Labyrinth) []byte {
+	positions := PlayerPositions(labyrinth)
+	var players []byte
+	for id := byte('0'); id <= '9'; id++ {
+		if labyrinth.Finished[id] || (labyrinth.Game != nil && labyrinth.Game.Captured[id]) {
+			continue
+		}
+		if _, ok := positions[id]; ok {
+			players = append(players, id)
+		}
+	}
+	return players
+}
+
+// PlayerPositions 一次扫描找出地图上所有玩家的位置，不在地图上的玩家没有条目
+// 同一玩家出现多次时取第一次出现的位置，与 FindPlayer 一致
+func PlayerPositions(labyrinth *Labyrinth) map[byte]Position {
+	positions := make(map[byte]Position)
+	for i := 0; i < labyrinth.Rows; i++ {
+		for j := 0; j < labyrinth.Cols; j++ {
+			ch := labyrinth.Map[i][j]
+			if _, ok := positions[ch]; !ok && IsPlayerTile(ch) {
+				positions[ch] = Position{i, j}
+			}
+		}
+	}
+	return positions
+}
+
+// CurrentTurn 当前轮到的玩家；没有记录或该玩家已离场时取下一个仍在场的玩家
+func CurrentTurn(labyrinth *Labyrinth) byte {
+	players := ActivePlayers(labyrinth)
+	if len(players) == 0 {
+		return 0
+	}
+	for _, id := range players {
+		if id >= labyrinth.Game.Turn {
+			return id
+		}
+	}
+	return players[0]
+}
+
+// PlayMove 在规则约束下移动玩家；地图没有规则时等同于 MovePlayer
+func PlayMove(labyrinth *Labyrinth, playerID byte, direction string) error {
+	game := labyrinth.Game
+	if game == nil {
+		return MovePlayer(labyrinth, playerID, direction)
+	}
+	if game.Over {
+		return fmt.Errorf("%w: game is over", ErrInvalidMove)
+	}
+	if game.Captured[playerID] {
+		return fmt.Errorf("%w: player has been captured", ErrInvalidMove)
+	}
+	if game.Rules.TurnOrder {
+		if turn := CurrentTurn(labyrinth); turn != playerID {
+			return fmt.Errorf("%w: not your turn, waiting for player %c", ErrInvalidMove, turn)
+		}
+	}
+	if game.Rules.MoveLimit > 0 && game.Moves[playerID] >= game.Rules.MoveLimit {
+		return fmt.Errorf("%w: move limit reached", ErrInvalidMove)
+	}
+
+	finished := labyrinth.Finished[playerID]
+	if game.Rules.Mode == ModeTag {
+		if err := captureMove(labyrinth, playerID, direction); err != nil {
+			return err
+		}
+	} else if err := MovePlayer(labyrinth, playerID, direction); err != nil {
+		return err
+	}
+	game.Moves[playerID]++
+	if _, ok := game.Scores[playerID]; !ok {
+		game.Scores[playerID] = 0
+	}
+	if !finished && labyrinth.Finished[playerID] {
+		game.Scores[playerID] += ExitScore
+	}
+	checkGameOver(labyrinth, playerID)
+	if game.Rules.TurnOrder && !game.Over {
+		game.Turn = nextTurn(labyrinth, playerID)
+	}
+	return nil
+}
+
+// captureMove 目标格子是其他玩家时先把对方移出地图再移动，移动成功才算抓住；
+// 移动失败（已到达出口、对方脚下的传送门被挡住等）时把对方放回原处
+func captureMove(labyrinth *Labyrinth, playerID byte, direction string) error {
+	p, err := FindPlayer(labyrinth, playerID)
+	if err != nil {
+		return err
+	}
+	for _, d := range Directions {
+		if d.Name != direction {
+			continue
+		}
+		row, col := p.Row+d.DRow, p.Col+d.DCol
+		if row < 0 || row >= labyrinth.Rows || col < 0 || col >= labyrinth.Cols {
+			break
+		}
+		other := labyrinth.Map[row][col]
+		if !IsPlayerTile(other) || other == playerID {
+			break
+		}
+		labyrinth.Map[row][col] = TileUnder(labyrinth, other)
+		if err := MovePlayer(labyrinth, playerID, direction); err != nil {
+			labyrinth.Map[row][col] = other
+			return err
+		}
+		delete(labyrinth.Under, other)
+		labyrinth.Game.Captured[other] = true
+		labyrinth.Game.Scores[playerID] += CaptureScore
+		return nil
+	}
+	return MovePlayer(labyrinth, playerID, direction)
+}
+
+// checkGameOver 检查胜利条件
+func checkGameOver(labyrinth *Labyrinth, mover byte) {
+	game := labyrinth.Game
+	if game.Rules.Mode == ModeRace && labyrinth.Finished[mover] {
+		game.Over, game.Winner = true, mover
+		return
+	}
+	if game.Rules.Mode != ModeTag && game.Rules.MoveLimit == 0 {
+		return
+	}
+	players := ActivePlayers(labyrinth)
+	if game.Rules.Mode == ModeTag && len(players) == 1 {
+		game.Over, game.Winner = true, players[0]
+		return
+	}
+	if game.Rules.MoveLimit == 0 {
+		return
+	}
+	for _, id := range players {
+		if game.Moves[id] < game.Rules.MoveLimit {
+			return
+		}
+	}
+	// 所有人用完步数，分数最高者获胜，并列则平局
+	game.Over, game.Winner = true, 0
+	best := -1
+	for id := byte('0'); id <= '9'; id++ {
+		score, ok := game.Scores[id]
+		if !ok {
+			continue
+		}
+		if score > best {
+			best, game.Winner = score, id
+		} else if score == best {
+			game.Winner = 0
+		}
+	}
+}
+
+// nextTurn 按 ID 顺序找到下一个仍在场的玩家
+func nextTurn(labyrinth *Labyrinth, current byte) byte {
+	players := ActivePlayers(labyrinth)
+	for _, id := range players {
+		if id > current {
+			return id
+		}
+	}
+	if len(players) > 0 {
+		return players[0]
+	}
+	return 0
+}
+
+// parseGameMeta 解析规则相关的元数据：
+//
+//	@rules MODE [turns] [limit=N]
+//	@turn ID
+//	@moves ID N
+//	@score ID N
+//	@captured ID
+//	@over ID|-
+func parseGameMeta(labyrinth *Labyrinth, key, value string) error {
+	fields := strings.Fields(value)
+	invalid := fmt.Errorf("invalid %s %q", key, value)
+	if key == "rules" {
+		if len(fields) == 0 {
+			return invalid
+		}
+		rules := Rules{Mode: fields[0]}
+		if rules.Mode != ModeFree && rules.Mode != ModeRace && rules.Mode != ModeTag {
+			return invalid
+		}
+		for _, f := range fields[1:] {
+			if f == "turns" {
+				rules.TurnOrder = true
+			} else if limit, ok := strings.CutPrefix(f, "limit="); ok {
+				n, err := strconv.Atoi(limit)
+				if err != nil || n < 0 {
+					return invalid
+				}
+				rules.MoveLimit = n
+			} else {
+				return invalid
+			}
+		}
+		if labyrinth.Game == nil {
+			labyrinth.Game = NewGameState(rules)
+		} else {
+			labyrinth.Game.Rules = rules
+		}
+		return nil
+	}
+
+	if labyrinth.Game == nil {
+		return fmt.Errorf("%s requires @rules", key)
+	}
+	game := labyrinth.Game
+	if key == "over" && len(fields) == 1 && fields[0] == "-" {
+		game.Over, game.Winner = true, 0
+		return nil
+	}
+	if len(fields) == 0 || len(fields[0]) != 1 || !IsValidPlayer(fields[0]) {
+		return invalid
+	}
+	id := byte(fields[0][0])
+	switch {
+	case key == "turn" && len(fields) == 1:
+		game.Turn = id
+	case key == "captured" && len(fields) == 1:
+		game.Captured[id] = true
+	case key == "over" && len(fields) == 1:
+		game.Over, game.Winner = true, id
+	case (key == "moves" || key == "score") && len(fields) == 2:
+		n, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return invalid
+		}
+		if key == "moves" {
+			game.Moves[id] = n
+		} else {
+			game.Scores[id] = n
+		}
+	default:
+		return invalid
+	}
+	return nil
+}
+
+// formatGameMeta 生成规则相关的元数据
+func formatGameMeta(labyrinth *Labyrinth) []string {
+	game := labyrinth.Game
+	if game == nil {
+		return nil
+	}
+	rules := game.Rules.Mode
+	if game.Rules.TurnOrder {
+		rules += " turns"
+	}
+	if game.Rules.MoveLimit > 0 {
+		rules += fmt.Sprintf(" limit=%d", game.Rules.MoveLimit)
+	}
+	meta := []string{MetaPrefix + "rules " + rules}
+	if game.Turn != 0 {
+		meta = append(meta, fmt.Sprintf("%sturn %c", MetaPrefix, game.Turn))
+	}
+	for _, id := range sortedPlayers(game.Moves) {
+		meta = append(meta, fmt.Sprintf("%smoves %c %d", MetaPrefix, id, game.Moves[id]))
+	}
+	for _, id := range sortedPlayers(game.Scores) {
+		meta = append(meta, fmt.Sprintf("%sscore %c %d", MetaPrefix, id, game.Scores[id]))
+	}
+	for _, id := range sortedPlayers(game.Captured) {
+		if game.Captured[id] {
+			meta = append(meta, fmt.Sprintf("%scaptured %c", MetaPrefix, id))
+		}
+	}
+	if game.Over {
+		if game.Winner == 0 {
+			meta = append(meta, MetaPrefix+"over -")
+		} else {
+			meta = append(meta, fmt.Sprintf("%sover %c", MetaPrefix, game.Winner))
+		}
+	}
+	return meta
+}
+
+// FormatStatus 比赛状态的文本描述
+func FormatStatus(labyrinth *Labyrinth) string {
+	var sb strings.Builder
+	game := labyrinth.Game
+	if game == nil {
+		sb.WriteString("mode: none\n")
+		fmt.Fprintf(&sb, "players: %s\n", string(ActivePlayers(labyrinth)))
+		return sb.String()
+	}
+	fmt.Fprintf(&sb, "mode: %s\n", game.Rules.Mode)
+	if game.Rules.TurnOrder && !game.Over {
+		if turn := CurrentTurn(labyrinth); turn != 0 {
+			fmt.Fprintf(&sb, "turn: %c\n", turn)
+		}
+	}
+	fmt.Fprintf(&sb, "players: %s\n", string(ActivePlayers(labyrinth)))
+	for _, id := range sortedPlayers(game.Scores) {
+		fmt.Fprintf(&sb, "player %c: score %d, moves %d\n", id, game.Scores[id], game.Moves[id])
+	}
+	switch {
+	case !game.Over:
+		sb.WriteString("game over: no\n")
+	case game.Winner == 0:
+		sb.WriteString("game over: yes, draw\n")
+	default:
+		fmt.Fprintf(&sb, "game over: yes, winner %c\n", game.Winner)
+	}
+	return sb.String()
+}
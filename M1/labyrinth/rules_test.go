@@ -0,0 +1,157 @@
+package labyrinth
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestTurnOrder 测试轮流移动
+func TestTurnOrder(t *testing.T) {
+	lab := newTestLabyrinth(
+		"0...",
+		"...1",
+	)
+	lab.Game = NewGameState(Rules{Mode: ModeFree, TurnOrder: true})
+	if err := PlayMove(lab, '1', "up"); err == nil {
+		t.Error("player 1 should wait for player 0")
+	}
+	if err := PlayMove(lab, '0', "right"); err != nil {
+		t.Fatalf("PlayMove(0) error: %v", err)
+	}
+	if err := PlayMove(lab, '0', "right"); err == nil {
+		t.Error("player 0 should not move twice in a row")
+	}
+	if err := PlayMove(lab, '1', "up"); err != nil {
+		t.Fatalf("PlayMove(1) error: %v", err)
+	}
+	if CurrentTurn(lab) != '0' {
+		t.Errorf("CurrentTurn() = %c, expected 0", CurrentTurn(lab))
+	}
+}
+
+// TestRaceToExit 测试抢先到达出口
+func TestRaceToExit(t *testing.T) {
+	lab := newTestLabyrinth("0E.1")
+	lab.Game = NewGameState(Rules{Mode: ModeRace})
+	if err := PlayMove(lab, '0', "right"); err != nil {
+		t.Fatalf("PlayMove() error: %v", err)
+	}
+	if !lab.Game.Over || lab.Game.Winner != '0' || lab.Game.Scores['0'] != ExitScore {
+		t.Errorf("race state = %+v", *lab.Game)
+	}
+	if err := PlayMove(lab, '1', "left"); err == nil {
+		t.Error("PlayMove() should fail after the game is over")
+	}
+}
+
+// TestTagCapture 测试抓人
+func TestTagCapture(t *testing.T) {
+	lab := newTestLabyrinth("01.2")
+	lab.Game = NewGameState(Rules{Mode: ModeTag})
+	if err := PlayMove(lab, '0', "right"); err != nil {
+		t.Fatalf("PlayMove() error: %v", err)
+	}
+	if string(lab.Map[0]) != ".0.2" || !lab.Game.Captured['1'] {
+		t.Errorf("after capture map = %q, captured = %v", string(lab.Map[0]), lab.Game.Captured)
+	}
+	if err := PlayMove(lab, '1', "left"); err == nil {
+		t.Error("captured player should not move")
+	}
+	PlayMove(lab, '0', "right")
+	PlayMove(lab, '0', "right")
+	if !lab.Game.Over || lab.Game.Winner != '0' || lab.Game.Scores['0'] != 2*CaptureScore {
+		t.Errorf("tag state = %+v", *lab.Game)
+	}
+}
+
+// TestTagFailedMove 测试移动失败时不抓人
+func TestTagFailedMove(t *testing.T) {
+	lab := newTestLabyrinth(
+		"######",
+		"#0E1.#",
+		"#...2#",
+	)
+	lab.Game = NewGameState(Rules{Mode: ModeTag})
+	if err := PlayMove(lab, '0', "right"); err != nil {
+		t.Fatalf("PlayMove() error: %v", err)
+	}
+	if err := PlayMove(lab, '0', "right"); err == nil {
+		t.Error("finished player should not move")
+	}
+	if string(lab.Map[1]) != "#.01.#" {
+		t.Errorf("after failed move map = %q", string(lab.Map[1]))
+	}
+	if lab.Game.Captured['1'] || lab.Game.Scores['0'] != ExitScore {
+		t.Errorf("failed move changed state: captured = %v, scores = %v", lab.Game.Captured, lab.Game.Scores)
+	}
+
+	// 对方站在传送门上，另一端被占住
+	lab = newTestLabyrinth("01.2")
+	lab.Under = map[byte]byte{'1': '*', '2': '*'}
+	lab.Game = NewGameState(Rules{Mode: ModeTag})
+	if err := PlayMove(lab, '0', "right"); err == nil {
+		t.Error("blocked teleporter should fail the move")
+	}
+	if string(lab.Map[0]) != "01.2" || lab.Game.Captured['1'] || lab.Game.Scores['0'] != 0 || lab.Under['1'] != '*' {
+		t.Errorf("failed move changed state: map = %q, captured = %v, scores = %v", string(lab.Map[0]), lab.Game.Captured, lab.Game.Scores)
+	}
+}
+
+// TestActivePlayers 测试一次扫描找出玩家，不在地图上的玩家不算在场
+func TestActivePlayers(t *testing.T) {
+	lab := newTestLabyrinth(
+		"2..0",
+		"..5.",
+	)
+	positions := PlayerPositions(lab)
+	if len(positions) != 3 || positions['5'] != (Position{1, 2}) {
+		t.Errorf("PlayerPositions() = %v", positions)
+	}
+	lab.Game = NewGameState(Rules{Mode: ModeTag})
+	lab.Game.Captured['5'] = true
+	if got := string(ActivePlayers(lab)); got != "02" {
+		t.Errorf("ActivePlayers() = %q, expected 02", got)
+	}
+}
+
+// TestMoveLimit 测试步数限制
+func TestMoveLimit(t *testing.T) {
+	lab := newTestLabyrinth("0..", "1..")
+	lab.Game = NewGameState(Rules{Mode: ModeFree, MoveLimit: 1})
+	PlayMove(lab, '0', "right")
+	if err := PlayMove(lab, '0', "right"); err == nil {
+		t.Error("PlayMove() should fail after the move limit")
+	}
+	PlayMove(lab, '1', "right")
+	if !lab.Game.Over || lab.Game.Winner != 0 {
+		t.Errorf("move limit state = %+v", *lab.Game)
+	}
+}
+
+// TestGameStateSaved 测试规则状态写入地图文件
+func TestGameStateSaved(t *testing.T) {
+	testFile := filepath.Join(t.TempDir(), "map.txt")
+	content := "0..\n..1\n@rules race turns limit=5\n@turn 1\n@moves 0 1\n@score 0 0\n"
+	if err := os.WriteFile(testFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	lab := &Labyrinth{}
+	if err := LoadMap(lab, testFile); err != nil {
+		t.Fatalf("LoadMap() error: %v", err)
+	}
+	if lab.Game == nil || !lab.Game.Rules.TurnOrder || lab.Game.Rules.MoveLimit != 5 || lab.Game.Turn != '1' {
+		t.Fatalf("LoadMap() game = %+v", lab.Game)
+	}
+	if err := SaveMap(lab, testFile); err != nil {
+		t.Fatalf("SaveMap() error: %v", err)
+	}
+	saved, _ := os.ReadFile(testFile)
+	if string(saved) != content {
+		t.Errorf("SaveMap() content = %q", saved)
+	}
+	if status := FormatStatus(lab); !strings.Contains(status, "turn: 1") || !strings.Contains(status, "game over: no") {
+		t.Errorf("FormatStatus() = %q", status)
+	}
+}
@@ -0,0 +1,268 @@
+package labyrinth
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+)
+
+// Server 持有唯一的内存地图，所有玩家的移动都在这里串行执行
+type Server struct {
+	mu        sync.Mutex
+	labyrinth *Labyrinth
+	mapFile   string
+	clients   map[*Client]struct{}
+	players   map[byte]*Client
+}
+
+// Client 一个玩家连接
+type Client struct {
+	conn     net.Conn
+	playerID byte
+	out      chan string
+}
+
+// NewServer 加载地图并检查连通性
+func NewServer(mapFile string) (*Server, error) {
+	labyrinth := &Labyrinth{}
+	if err := LoadMap(labyrinth, mapFile); err != nil {
+		return nil, err
+	}
+	if err := IsConnected(labyrinth); err != nil {
+		return nil, err
+	}
+	return &Server{
+		labyrinth: labyrinth,
+		mapFile:   mapFile,
+		clients:   make(map[*Client]struct{}),
+		players:   make(map[byte]*Client),
+	}, nil
+}
+
+// Serve 接受连接，每个连接一个 goroutine
+func (s *Server) Serve(ln net.Listener) error {
+	defer ln.Close()
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		go s.handle(conn)
+	}
+}
+
+// handle 逐行读取命令：JOIN id / MOVE dir / LOOK / QUIT
+func (s *Server) handle(conn net.Conn) {
+	client := &Client{conn: conn, out: make(chan string, 64)}
+	s.mu.Lock()
+	s.clients[client] = struct{}{}
+	s.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		writer := bufio.NewWriter(conn)
+		for msg := range client.out {
+			if _, err := writer.WriteString(msg); err != nil {
+				return
+			}
+			if err := writer.Flush(); err != nil {
+				return
+			}
+		}
+	}()
+
+	scanner := bufio.NewScanner(conn)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if strings.ToUpper(fields[0]) == "QUIT" {
+			break
+		}
+		s.dispatch(client, fields)
+	}
+
+	s.mu.Lock()
+	delete(s.clients, client)
+	if client.playerID != 0 && s.players[client.playerID] == client {
+		delete(s.players, client.playerID)
+	}
+	close(client.out)
+	s.mu.Unlock()
+	<-done
+	conn.Close()
+}
+
+func (s *Server) dispatch(client *Client, fields []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch strings.ToUpper(fields[0]) {
+	case "JOIN":
+		if len(fields) != 2 || len(fields[1]) != 1 || !IsValidPlayer(fields[1]) {
+			s.reply(client, "ERR usage: JOIN id\n")
+			return
+		}
+		if err := s.join(client, byte(fields[1][0])); err != nil {
+			s.reply(client, fmt.Sprintf("ERR %v\n", err))
+			return
+		}
+		p, _ := FindPlayer(s.labyrinth, client.playerID)
+		s.reply(client, fmt.Sprintf("OK JOIN %c %d %d\n", client.playerID, p.Row, p.Col))
+		s.broadcast()
+	case "MOVE":
+		if len(fields) != 2 {
+			s.reply(client, "ERR usage: MOVE direction\n")
+			return
+		}
+		if client.playerID == 0 {
+			s.reply(client, "ERR not joined\n")
+			return
+		}
+		// 直接在内存地图上移动，地图与日志都写入后才回复；失败时按日志记录撤回
+		event, err := playStep(s.labyrinth, client.playerID, fields[1])
+		if err != nil {
+			s.reply(client, fmt.Sprintf("ERR %v\n", err))
+			return
+		}
+		entry := journalEntry(event)
+		undo := func() error { return undoEntry(s.labyrinth, entry) }
+		if err := s.persist(&entry, undo); err != nil {
+			s.reply(client, fmt.Sprintf("ERR %v\n", err))
+			// 地图被其他进程改过时已经重新加载，把新的地图推给所有人
+			if errors.Is(err, ErrStaleMap) {
+				s.broadcast()
+			}
+			return
+		}
+		s.reply(client, fmt.Sprintf("OK MOVE %d %d\n", event.To.Row, event.To.Col))
+		s.broadcast()
+	case "LOOK":
+		s.reply(client, renderMap(s.labyrinth))
+	default:
+		s.reply(client, fmt.Sprintf("ERR unknown command %s\n", fields[0]))
+	}
+}
+
+// join 把连接绑定到玩家；地图上没有该玩家时放到第一个空地
+func (s *Server) join(client *Client, playerID byte) error {
+	if client.playerID != 0 {
+		return errors.New("already joined")
+	}
+	if _, ok := s.players[playerID]; ok {
+		return errors.New("player already taken")
+	}
+	p, err := FindPlayer(s.labyrinth, playerID)
+	if err != nil {
+		return err
+	}
+	if tile := s.labyrinth.Map[p.Row][p.Col]; tile != playerID {
+		s.labyrinth.Map[p.Row][p.Col] = playerID
+		undo := func() error {
+			s.labyrinth.Map[p.Row][p.Col] = tile
+			return nil
+		}
+		if err := s.persist(nil, undo); err != nil {
+			return err
+		}
+	}
+	client.playerID = playerID
+	s.players[playerID] = client
+	return nil
+}
+
+// persist 在文件锁下用 CommitMap 提交已经作用在 s.labyrinth 上的修改，entry 不为空时同时追加移动日志
+// 地图被 CLI、机器人等其他写入者改过时返回 ErrStaleMap，并从文件重新加载内存中的地图；
+// 保存失败时用 undo 撤回内存中的修改，日志写入失败时撤回后重新提交，返回错误时这次修改没有生效
+func (s *Server) persist(entry *JournalEntry, undo func() error) error {
+	lock, err := LockMap(s.mapFile)
+	if err != nil {
+		return s.rollback(fmt.Errorf("lock map: %w", err), undo)
+	}
+	defer lock.Unlock()
+	if err := CommitMap(s.labyrinth, s.mapFile); errors.Is(err, ErrStaleMap) {
+		if reloadErr := s.reload(); reloadErr != nil {
+			return s.rollback(fmt.Errorf("%w, reload map: %v", err, reloadErr), undo)
+		}
+		return err
+	} else if err != nil {
+		return s.rollback(fmt.Errorf("save map: %w", err), undo)
+	}
+	if entry == nil {
+		return nil
+	}
+	if err := AppendJournal(JournalPath(s.mapFile), *entry); err != nil {
+		err = fmt.Errorf("write journal: %w", err)
+		// 撤回后以更新的版本号重新提交，版本号不会回退
+		if undoErr := undo(); undoErr != nil {
+			err = fmt.Errorf("%w, undo move: %v", err, undoErr)
+		} else if commitErr := CommitMap(s.labyrinth, s.mapFile); commitErr == nil {
+			return err
+		} else {
+			err = fmt.Errorf("%w, restore map: %v", err, commitErr)
+		}
+		// 内存与文件无法恢复一致时以文件为准
+		if reloadErr := s.reload(); reloadErr != nil {
+			return fmt.Errorf("%w, reload map: %v", err, reloadErr)
+		}
+		return err
+	}
+	return nil
+}
+
+// rollback 撤回没有保存的修改，返回 err 以及撤回时的错误
+func (s *Server) rollback(err error, undo func() error) error {
+	if undoErr := undo(); undoErr != nil {
+		return fmt.Errorf("%w, undo move: %v", err, undoErr)
+	}
+	return err
+}
+
+// reload 从文件重新加载内存中的地图，调用方需持有 s.mu 与文件锁
+func (s *Server) reload() error {
+	labyrinth := &Labyrinth{}
+	if err := LoadMap(labyrinth, s.mapFile); err != nil {
+		return err
+	}
+	s.labyrinth = labyrinth
+	return nil
+}
+
+// broadcast 把最新地图推给所有连接，调用方需持有 s.mu
+// 每次广播都是完整地图，缓冲区过半的慢连接跳过这一次，留出空间给命令的回复
+func (s *Server) broadcast() {
+	msg := renderMap(s.labyrinth)
+	for client := range s.clients {
+		if len(client.out) < cap(client.out)/2 {
+			client.out <- msg
+		}
+	}
+}
+
+// reply 发送命令的回复，回复不能丢；缓冲区满说明连接跟不上，直接断开
+func (s *Server) reply(client *Client, msg string) {
+	select {
+	case client.out <- msg:
+	default:
+		client.conn.Close()
+	}
+}
+
+// renderMap 协议中的地图块：MAP rows cols，随后逐行地图，以 END 结束
+func renderMap(labyrinth *Labyrinth) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "MAP %d %d\n", labyrinth.Rows, labyrinth.Cols)
+	for i := 0; i < labyrinth.Rows; i++ {
+		sb.WriteString(string(labyrinth.Map[i]))
+		sb.WriteByte('\n')
+	}
+	sb.WriteString("END\n")
+	return sb.String()
+}
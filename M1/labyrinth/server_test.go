@@ -0,0 +1,247 @@
+package labyrinth
+
+import (
+	"bufio"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// readLine 读取一行协议响应，跳过广播的地图块
+func readLine(t *testing.T, r *bufio.Reader) string {
+	t.Helper()
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("read error: %v", err)
+		}
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "MAP ") {
+			for {
+				l, err := r.ReadString('\n')
+				if err != nil {
+					t.Fatalf("read error: %v", err)
+				}
+				if strings.TrimSpace(l) == "END" {
+					break
+				}
+			}
+			continue
+		}
+		return line
+	}
+}
+
+// TestServerJoinMove 测试服务器的 JOIN/MOVE 协议
+func TestServerJoinMove(t *testing.T) {
+	mapFile := filepath.Join(t.TempDir(), "map.txt")
+	if err := os.WriteFile(mapFile, []byte("#####\n#0..#\n#...#\n#####\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	server, err := NewServer(mapFile)
+	if err != nil {
+		t.Fatalf("NewServer() error: %v", err)
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen error: %v", err)
+	}
+	go server.Serve(ln)
+	defer ln.Close()
+
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatalf("Dial error: %v", err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	r := bufio.NewReader(conn)
+
+	conn.Write([]byte("MOVE right\n"))
+	if line := readLine(t, r); !strings.HasPrefix(line, "ERR") {
+		t.Errorf("MOVE before JOIN = %q, expected ERR", line)
+	}
+	conn.Write([]byte("JOIN 0\n"))
+	if line := readLine(t, r); line != "OK JOIN 0 1 1" {
+		t.Errorf("JOIN 0 = %q, expected OK JOIN 0 1 1", line)
+	}
+	conn.Write([]byte("MOVE right\n"))
+	if line := readLine(t, r); line != "OK MOVE 1 2" {
+		t.Errorf("MOVE right = %q, expected OK MOVE 1 2", line)
+	}
+	conn.Write([]byte("MOVE up\n"))
+	if line := readLine(t, r); !strings.HasPrefix(line, "ERR") {
+		t.Errorf("MOVE up into wall = %q, expected ERR", line)
+	}
+
+	// 第二个连接不能再占用同一个玩家
+	other, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatalf("Dial error: %v", err)
+	}
+	defer other.Close()
+	other.SetDeadline(time.Now().Add(5 * time.Second))
+	or := bufio.NewReader(other)
+	other.Write([]byte("JOIN 0\n"))
+	if line := readLine(t, or); !strings.HasPrefix(line, "ERR") {
+		t.Errorf("second JOIN 0 = %q, expected ERR", line)
+	}
+
+	// 移动已经持久化到地图文件
+	lab := &Labyrinth{}
+	if err := LoadMap(lab, mapFile); err != nil {
+		t.Fatalf("LoadMap() error: %v", err)
+	}
+	if pos, _ := FindPlayer(lab, '0'); pos.Row != 1 || pos.Col != 2 {
+		t.Errorf("saved player at (%d, %d), expected (1, 2)", pos.Row, pos.Col)
+	}
+}
+
+// TestServerSaveError 测试保存失败时回复 ERR 且内存地图不变
+func TestServerSaveError(t *testing.T) {
+	mapFile := filepath.Join(t.TempDir(), "map.txt")
+	if err := os.WriteFile(mapFile, []byte("#####\n#0..#\n#####\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	server, err := NewServer(mapFile)
+	if err != nil {
+		t.Fatalf("NewServer() error: %v", err)
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen error: %v", err)
+	}
+	go server.Serve(ln)
+	defer ln.Close()
+
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatalf("Dial error: %v", err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	r := bufio.NewReader(conn)
+	conn.Write([]byte("JOIN 0\n"))
+	if line := readLine(t, r); line != "OK JOIN 0 1 1" {
+		t.Fatalf("JOIN 0 = %q, expected OK JOIN 0 1 1", line)
+	}
+
+	// 地图文件的位置被非空目录占住，rename 失败
+	if err := os.Remove(mapFile); err != nil {
+		t.Fatalf("Remove error: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Join(mapFile, "sub"), 0755); err != nil {
+		t.Fatalf("MkdirAll error: %v", err)
+	}
+	conn.Write([]byte("MOVE right\n"))
+	if line := readLine(t, r); !strings.HasPrefix(line, "ERR save map") {
+		t.Errorf("MOVE with unwritable map = %q, expected ERR save map", line)
+	}
+
+	// 恢复后从原位置重新移动
+	if err := os.RemoveAll(mapFile); err != nil {
+		t.Fatalf("RemoveAll error: %v", err)
+	}
+	if err := os.WriteFile(mapFile, []byte("#####\n#0..#\n#####\n"), 0644); err != nil {
+		t.Fatalf("Failed to restore test file: %v", err)
+	}
+	conn.Write([]byte("MOVE right\n"))
+	if line := readLine(t, r); line != "OK MOVE 1 2" {
+		t.Errorf("MOVE right = %q, expected OK MOVE 1 2", line)
+	}
+
+	// 日志写不进去时撤回已保存的地图，版本号继续增加
+	if err := os.Remove(JournalPath(mapFile)); err != nil {
+		t.Fatalf("Remove error: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Join(JournalPath(mapFile), "sub"), 0755); err != nil {
+		t.Fatalf("MkdirAll error: %v", err)
+	}
+	conn.Write([]byte("MOVE right\n"))
+	if line := readLine(t, r); !strings.HasPrefix(line, "ERR write journal") {
+		t.Errorf("MOVE with unwritable journal = %q, expected ERR write journal", line)
+	}
+	lab := &Labyrinth{}
+	if err := LoadMap(lab, mapFile); err != nil {
+		t.Fatalf("LoadMap() error: %v", err)
+	}
+	if string(lab.Map[1]) != "#.0.#" || lab.Version != 3 {
+		t.Errorf("after failed journal map = %q, version %d, expected #.0.# version 3", string(lab.Map[1]), lab.Version)
+	}
+	if err := os.RemoveAll(JournalPath(mapFile)); err != nil {
+		t.Fatalf("RemoveAll error: %v", err)
+	}
+	conn.Write([]byte("MOVE right\n"))
+	if line := readLine(t, r); line != "OK MOVE 1 3" {
+		t.Errorf("MOVE right = %q, expected OK MOVE 1 3", line)
+	}
+}
+
+// TestServerStaleMap 测试其他进程提交过地图时服务器不覆盖，重新加载后继续
+func TestServerStaleMap(t *testing.T) {
+	mapFile := filepath.Join(t.TempDir(), "map.txt")
+	if err := os.WriteFile(mapFile, []byte("#####\n#0..#\n#1..#\n#####\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	server, err := NewServer(mapFile)
+	if err != nil {
+		t.Fatalf("NewServer() error: %v", err)
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen error: %v", err)
+	}
+	go server.Serve(ln)
+	defer ln.Close()
+
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatalf("Dial error: %v", err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	r := bufio.NewReader(conn)
+	conn.Write([]byte("JOIN 0\n"))
+	if line := readLine(t, r); line != "OK JOIN 0 1 1" {
+		t.Fatalf("JOIN 0 = %q, expected OK JOIN 0 1 1", line)
+	}
+
+	// 命令行在服务器之外移动了玩家 1
+	game := &Game{}
+	if err := game.Load(mapFile); err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	if _, err := game.Move('1', "right"); err != nil {
+		t.Fatalf("Move() error: %v", err)
+	}
+	if err := game.Save(); err != nil {
+		t.Fatalf("Save() error: %v", err)
+	}
+
+	conn.Write([]byte("MOVE right\n"))
+	if line := readLine(t, r); !strings.HasPrefix(line, "ERR "+ErrStaleMap.Error()) {
+		t.Errorf("MOVE on stale map = %q, expected ERR %v", line, ErrStaleMap)
+	}
+	lab := &Labyrinth{}
+	if err := LoadMap(lab, mapFile); err != nil {
+		t.Fatalf("LoadMap() error: %v", err)
+	}
+	if string(lab.Map[2]) != "#.1.#" || lab.Version != 1 {
+		t.Errorf("stale MOVE overwrote the map: %q, version %d", string(lab.Map[2]), lab.Version)
+	}
+
+	// 重新加载后的移动保留命令行的修改
+	conn.Write([]byte("MOVE right\n"))
+	if line := readLine(t, r); line != "OK MOVE 1 2" {
+		t.Errorf("MOVE right = %q, expected OK MOVE 1 2", line)
+	}
+	if err := LoadMap(lab, mapFile); err != nil {
+		t.Fatalf("LoadMap() error: %v", err)
+	}
+	if string(lab.Map[1]) != "#.0.#" || string(lab.Map[2]) != "#.1.#" || lab.Version != 2 {
+		t.Errorf("after reload map = %q %q, version %d", string(lab.Map[1]), string(lab.Map[2]), lab.Version)
+	}
+}
@@ -0,0 +1,210 @@
+package labyrinth
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// 扩展地块：
+//
+//	a-z 钥匙，走上去即拾取
+//	A-Z 门，持有对应小写钥匙的玩家才能打开，打开后变为空地
+//	TeleporterTiles 中的符号成对出现，走上一个会被传送到另一个
+//	E   出口，到达后该玩家结束游戏
+//	< > 楼梯，见 floors.go
+const (
+	ExitTile        = 'E'
+	TeleporterTiles = "!$%&*+=?"
+)
+
+// IsKey 是否为钥匙
+func IsKey(ch byte) bool {
+	return ch >= 'a' && ch <= 'z'
+}
+
+// IsDoor 是否为门（出口 E 除外）
+func IsDoor(ch byte) bool {
+	return ch >= 'A' && ch <= 'Z' && ch != ExitTile
+}
+
+// DoorKey 打开门所需的钥匙
+func DoorKey(door byte) byte {
+	return door - 'A' + 'a'
+}
+
+// IsTeleporter 是否为传送门
+func IsTeleporter(ch byte) bool {
+	return strings.IndexByte(TeleporterTiles, ch) >= 0
+}
+
+// IsPlayerTile 是否为玩家
+func IsPlayerTile(ch byte) bool {
+	return ch >= '0' && ch <= '9'
+}
+
+// IsKnownTile 是否为地图中合法的字符
+func IsKnownTile(ch byte) bool {
+	return ch == '#' || ch == '.' || ch == ExitTile || IsKey(ch) || IsDoor(ch) || IsTeleporter(ch) || IsStairs(ch) || IsPlayerTile(ch)
+}
+
+// HasKey 玩家是否持有钥匙
+func HasKey(labyrinth *Labyrinth, playerID byte, key byte) bool {
+	return strings.IndexByte(labyrinth.Inventory[playerID], key) >= 0
+}
+
+// TileUnder 玩家脚下的地块，默认为空地
+func TileUnder(labyrinth *Labyrinth, playerID byte) byte {
+	if tile, ok := labyrinth.Under[playerID]; ok {
+		return tile
+	}
+	return '.'
+}
+
+// TeleporterPositions 所有传送门的位置，包括被玩家站着的
+func TeleporterPositions(labyrinth *Labyrinth) map[byte][]Position {
+	positions := make(map[byte][]Position)
+	for i := 0; i < labyrinth.Rows; i++ {
+		for j := 0; j < labyrinth.Cols; j++ {
+			ch := labyrinth.Map[i][j]
+			if IsPlayerTile(ch) {
+				ch = TileUnder(labyrinth, ch)
+			}
+			if IsTeleporter(ch) {
+				positions[ch] = append(positions[ch], Position{i, j})
+			}
+		}
+	}
+	return positions
+}
+
+// checkTiles 检查传送门成对出现
+func checkTiles(labyrinth *Labyrinth) error {
+	for tile, positions := range TeleporterPositions(labyrinth) {
+		if len(positions) != 2 {
+			return fmt.Errorf("teleporter %c must appear exactly twice", tile)
+		}
+	}
+	if unlinked := UnlinkedStairs(labyrinth); len(unlinked) > 0 {
+		return fmt.Errorf("stairs at (%d, %d) do not lead to matching stairs", unlinked[0].Row, unlinked[0].Col)
+	}
+	return nil
+}
+
+// enterTile 计算玩家走到 target 之后的落点，并更新钥匙、门、出口等状态
+// 返回最终位置与落点下方的地块
+func enterTile(labyrinth *Labyrinth, playerID byte, target Position) (Position, byte, error) {
+	if target.Row < 0 || target.Row >= labyrinth.Rows || target.Col < 0 || target.Col >= labyrinth.Cols {
+		return target, 0, ErrInvalidMove
+	}
+	tile := labyrinth.Map[target.Row][target.Col]
+	switch {
+	case tile == '.':
+		return target, '.', nil
+	case IsKey(tile):
+		if !HasKey(labyrinth, playerID, tile) {
+			addKey(labyrinth, playerID, tile)
+		}
+		return target, '.', nil
+	case IsDoor(tile):
+		if !HasKey(labyrinth, playerID, DoorKey(tile)) {
+			return target, 0, fmt.Errorf("%w: door is locked", ErrInvalidMove)
+		}
+		return target, '.', nil
+	case IsTeleporter(tile):
+		for _, p := range TeleporterPositions(labyrinth)[tile] {
+			if p == target {
+				continue
+			}
+			if labyrinth.Map[p.Row][p.Col] != tile {
+				return target, 0, fmt.Errorf("%w: teleporter is blocked", ErrInvalidMove)
+			}
+			return p, tile, nil
+		}
+		return target, 0, fmt.Errorf("%w: teleporter is unpaired", ErrInvalidMove)
+	case IsStairs(tile):
+		return target, tile, nil
+	case tile == ExitTile:
+		if labyrinth.Finished == nil {
+			labyrinth.Finished = make(map[byte]bool)
+		}
+		labyrinth.Finished[playerID] = true
+		return target, ExitTile, nil
+	}
+	return target, 0, ErrInvalidMove
+}
+
+func addKey(labyrinth *Labyrinth, playerID byte, key byte) {
+	if labyrinth.Inventory == nil {
+		labyrinth.Inventory = make(map[byte]string)
+	}
+	keys := []byte(labyrinth.Inventory[playerID] + string(key))
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	labyrinth.Inventory[playerID] = string(keys)
+}
+
+// reachable 计算从第一个空地出发可以到达的格子，结果按 cellIndex 编号
+// 钥匙集合从所有玩家的背包开始，每轮加入新到达的钥匙，直到不再增加
+// 逐层 BFS 只保存当前一层的边界，大地图上内存占用为位图加上边界宽度
+func reachable(labyrinth *Labyrinth, start Position) Bitset {
+	keys := make(map[byte]bool)
+	for _, inventory := range labyrinth.Inventory {
+		for _, key := range []byte(inventory) {
+			keys[key] = true
+		}
+	}
+	teleporters := TeleporterPositions(labyrinth)
+	rows, cols := labyrinth.Rows, labyrinth.Cols
+	for {
+		visited := NewBitset(rows * cols)
+		visited.Set(cellIndex(labyrinth, start))
+		frontier := []int32{int32(cellIndex(labyrinth, start))}
+		var next []int32
+		newKey := false
+		visit := func(row, col int) {
+			if row < 0 || row >= rows || col < 0 || col >= cols {
+				return
+			}
+			i := row*cols + col
+			if visited.Get(i) {
+				return
+			}
+			ch := labyrinth.Map[row][col]
+			if ch == '#' || (IsDoor(ch) && !keys[DoorKey(ch)]) {
+				return
+			}
+			visited.Set(i)
+			next = append(next, int32(i))
+		}
+		for len(frontier) > 0 {
+			next = next[:0]
+			for _, i := range frontier {
+				row, col := int(i)/cols, int(i)%cols
+				tile := labyrinth.Map[row][col]
+				if IsPlayerTile(tile) {
+					tile = TileUnder(labyrinth, tile)
+				}
+				if IsKey(tile) && !keys[tile] {
+					keys[tile] = true
+					newKey = true
+				}
+				visit(row-1, col)
+				visit(row+1, col)
+				visit(row, col-1)
+				visit(row, col+1)
+				if IsTeleporter(tile) {
+					for _, p := range teleporters[tile] {
+						visit(p.Row, p.Col)
+					}
+				}
+				if link, _, ok := StairLink(labyrinth, Position{row, col}, tile); ok {
+					visit(link.Row, link.Col)
+				}
+			}
+			frontier, next = next, frontier
+		}
+		if !newKey {
+			return visited
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package labyrinth
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestKeysAndDoors 测试拾取钥匙并开门
+func TestKeysAndDoors(t *testing.T) {
+	lab := newTestLabyrinth("A0a.")
+	if err := MovePlayer(lab, '0', "left"); err == nil {
+		t.Error("MovePlayer() should not open a door without its key")
+	}
+	if err := MovePlayer(lab, '0', "right"); err != nil {
+		t.Fatalf("MovePlayer(right) error: %v", err)
+	}
+	if !HasKey(lab, '0', 'a') {
+		t.Error("player should pick up key a")
+	}
+	MovePlayer(lab, '0', "left")
+	if err := MovePlayer(lab, '0', "left"); err != nil {
+		t.Fatalf("MovePlayer() with key should open the door: %v", err)
+	}
+	if string(lab.Map[0]) != "0..." {
+		t.Errorf("map after opening door = %q", string(lab.Map[0]))
+	}
+}
+
+// TestTeleporter 测试传送门
+func TestTeleporter(t *testing.T) {
+	lab := newTestLabyrinth(
+		"0!#.",
+		"..#!",
+	)
+	if err := MovePlayer(lab, '0', "right"); err != nil {
+		t.Fatalf("MovePlayer() error: %v", err)
+	}
+	if pos, _ := FindPlayer(lab, '0'); *pos != (Position{1, 3}) {
+		t.Errorf("after teleport player at %v, expected (1, 3)", *pos)
+	}
+	if lab.Map[0][1] != '!' || TileUnder(lab, '0') != '!' {
+		t.Error("teleporter tiles should be kept")
+	}
+	if err := MovePlayer(lab, '0', "up"); err != nil {
+		t.Fatalf("MovePlayer() error: %v", err)
+	}
+	if lab.Map[1][3] != '!' {
+		t.Errorf("teleporter should be restored after leaving, got %c", lab.Map[1][3])
+	}
+}
+
+// TestExit 测试到达出口后不能再移动
+func TestExit(t *testing.T) {
+	lab := newTestLabyrinth("0E.")
+	if err := MovePlayer(lab, '0', "right"); err != nil {
+		t.Fatalf("MovePlayer() error: %v", err)
+	}
+	if !lab.Finished['0'] {
+		t.Error("player should finish on the exit")
+	}
+	if err := MovePlayer(lab, '0', "right"); err == nil {
+		t.Error("finished player should not move")
+	}
+}
+
+// TestIsConnectedWithDoors 测试连通性考虑能否拿到钥匙
+func TestIsConnectedWithDoors(t *testing.T) {
+	if err := IsConnected(newTestLabyrinth(".#a", "A..")); err == nil {
+		t.Error("IsConnected() should fail when the door blocks the only path")
+	}
+	if err := IsConnected(newTestLabyrinth(".aA.")); err != nil {
+		t.Errorf("IsConnected() error: %v", err)
+	}
+	if err := IsConnected(newTestLabyrinth(".Aa.")); err == nil {
+		t.Error("IsConnected() should fail when the key is behind its own door")
+	}
+	if err := IsConnected(newTestLabyrinth(".!#!.")); err != nil {
+		t.Errorf("IsConnected() through teleporter error: %v", err)
+	}
+}
+
+// TestInventorySaved 测试背包随地图保存
+func TestInventorySaved(t *testing.T) {
+	testFile := filepath.Join(t.TempDir(), "map.txt")
+	if err := os.WriteFile(testFile, []byte(".0b\n!.!\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	lab := &Labyrinth{}
+	if err := LoadMap(lab, testFile); err != nil {
+		t.Fatalf("LoadMap() error: %v", err)
+	}
+	MovePlayer(lab, '0', "right")
+	MovePlayer(lab, '0', "down")
+	if err := SaveMap(lab, testFile); err != nil {
+		t.Fatalf("SaveMap() error: %v", err)
+	}
+	content, _ := os.ReadFile(testFile)
+	if string(content) != "...\n0.!\n@inventory 0 b\n@under 0 !\n" {
+		t.Errorf("SaveMap() content = %q", content)
+	}
+	again := &Labyrinth{}
+	if err := LoadMap(again, testFile); err != nil {
+		t.Fatalf("LoadMap() error: %v", err)
+	}
+	if again.Inventory['0'] != "b" || TileUnder(again, '0') != '!' {
+		t.Errorf("LoadMap() inventory=%q under=%c", again.Inventory['0'], TileUnder(again, '0'))
+	}
+}
@@ -0,0 +1,219 @@
+package labyrinth
+
+import (
+	"fmt"
+	"strings"
+)
+
+// 问题类型
+const (
+	ProblemEmpty           = "empty"
+	ProblemTooLarge        = "too-large"
+	ProblemRaggedRow       = "ragged-row"
+	ProblemUnknownChar     = "unknown-char"
+	ProblemDuplicatePlayer = "duplicate-player"
+	ProblemMetadata        = "metadata"
+	ProblemTeleporter      = "teleporter"
+	ProblemFloors          = "floors"
+	ProblemStairs          = "stairs"
+	ProblemDisconnected    = "disconnected"
+)
+
+// Problem 一条诊断
+type Problem struct {
+	Kind    string     `json:"kind"`
+	Message string     `json:"message"`
+	Cells   []Position `json:"cells,omitempty"`
+}
+
+// Report 地图校验结果
+type Report struct {
+	File     string    `json:"file"`
+	Rows     int       `json:"rows"`
+	Cols     int       `json:"cols"`
+	Problems []Problem `json:"problems"`
+}
+
+// OK 没有任何问题
+func (r *Report) OK() bool {
+	return len(r.Problems) == 0
+}
+
+func (r *Report) add(kind string, cells []Position, format string, args ...any) {
+	r.Problems = append(r.Problems, Problem{Kind: kind, Message: fmt.Sprintf(format, args...), Cells: cells})
+}
+
+// ValidateFile 读取并检查地图文件
+func ValidateFile(filename string) (*Report, error) {
+	lines, err := readFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	report := ValidateMap(lines)
+	report.File = filename
+	return report, nil
+}
+
+// ValidateMap 检查地图文件内容，收集所有问题而不是在第一个问题处停止
+func ValidateMap(lines []string) *Report {
+	report := &Report{Problems: []Problem{}}
+	grid, meta := splitMeta(lines)
+	if len(grid) == 0 {
+		report.add(ProblemEmpty, nil, "map is empty")
+		return report
+	}
+
+	// 楼层分隔行与 LoadMap 一样当作一行墙
+	floorStart, floorRows, floor := 0, 0, 0
+	separators := make(map[int]bool)
+	checkFloor := func(end int) {
+		if err := checkFloorHeight(end-floorStart, &floorRows); err != nil {
+			report.add(ProblemFloors, nil, "floor %d: %v", floor, err)
+		}
+		floor++
+	}
+	for i, line := range grid {
+		if line == FloorSeparator {
+			checkFloor(i)
+			separators[i] = true
+			floorStart = i + 1
+		}
+	}
+	if len(separators) > 0 {
+		checkFloor(len(grid))
+	}
+
+	// 每一行都与第一行地图比较宽度，cols 取最宽的一行用于补墙与大小检查
+	expected := 0
+	for i, line := range grid {
+		if !separators[i] {
+			expected = len(line)
+			break
+		}
+	}
+	cols := expected
+	for i, line := range grid {
+		if separators[i] {
+			continue
+		}
+		if len(line) != expected {
+			report.add(ProblemRaggedRow, nil, "row %d has %d columns, expected %d", i, len(line), expected)
+		}
+		cols = max(cols, len(line))
+	}
+	report.Rows, report.Cols = len(grid), cols
+	if len(grid) > MaxRows || cols > MaxCols {
+		report.add(ProblemTooLarge, nil, "map is %dx%d, limit is %dx%d", len(grid), cols, MaxRows, MaxCols)
+	}
+
+	// 每行只复制一次，参差不齐的行补墙，后续检查在矩形地图上进行
+	labyrinth := &Labyrinth{Rows: len(grid), Cols: cols, FloorRows: floorRows, Map: make([][]byte, len(grid))}
+	seen := make(map[byte][]Position)
+	for i, line := range grid {
+		row := make([]byte, cols)
+		n := 0
+		if !separators[i] {
+			n = copy(row, line)
+		}
+		for j := n; j < cols; j++ {
+			row[j] = '#'
+		}
+		labyrinth.Map[i] = row
+		for j, ch := range row[:n] {
+			if !IsKnownTile(ch) {
+				report.add(ProblemUnknownChar, []Position{{i, j}}, "unknown character %q at (%d, %d)", ch, i, j)
+				row[j] = '#'
+			}
+			if IsPlayerTile(ch) {
+				seen[ch] = append(seen[ch], Position{i, j})
+			}
+		}
+	}
+	for id := byte('0'); id <= '9'; id++ {
+		if len(seen[id]) > 1 {
+			report.add(ProblemDuplicatePlayer, seen[id], "player %c appears %d times", id, len(seen[id]))
+		}
+	}
+
+	labyrinth.Inventory = make(map[byte]string)
+	labyrinth.Under = make(map[byte]byte)
+	labyrinth.Finished = make(map[byte]bool)
+	for _, line := range meta {
+		if err := parseMeta(labyrinth, []string{line}); err != nil {
+			report.add(ProblemMetadata, nil, "%v", err)
+		}
+	}
+	for tile, positions := range TeleporterPositions(labyrinth) {
+		if len(positions) != 2 {
+			report.add(ProblemTeleporter, positions, "teleporter %c appears %d times, expected 2", tile, len(positions))
+		}
+	}
+	for _, p := range UnlinkedStairs(labyrinth) {
+		report.add(ProblemStairs, []Position{p}, "stairs at (%d, %d) do not lead to matching stairs", p.Row, p.Col)
+	}
+
+	for _, region := range DisconnectedRegions(labyrinth) {
+		report.add(ProblemDisconnected, region, "region of %d cells starting at (%d, %d) is not reachable",
+			len(region), region[0].Row, region[0].Col)
+	}
+	return report
+}
+
+// DisconnectedRegions 返回从第一个空地出发无法到达的区域，每个区域按相邻关系分组
+func DisconnectedRegions(labyrinth *Labyrinth) [][]Position {
+	start, err := FindFirstEmptySpace(labyrinth)
+	if err != nil {
+		return nil
+	}
+	visited := reachable(labyrinth, *start)
+	var regions [][]Position
+	for i := 0; i < labyrinth.Rows; i++ {
+		for j := 0; j < labyrinth.Cols; j++ {
+			if visited.Get(i*labyrinth.Cols+j) || labyrinth.Map[i][j] == '#' {
+				continue
+			}
+			visited.Set(i*labyrinth.Cols + j)
+			region := []Position{{i, j}}
+			for k := 0; k < len(region); k++ {
+				cur := region[k]
+				for _, d := range Directions {
+					r, c := cur.Row+d.DRow, cur.Col+d.DCol
+					if r < 0 || r >= labyrinth.Rows || c < 0 || c >= labyrinth.Cols || visited.Get(r*labyrinth.Cols+c) || labyrinth.Map[r][c] == '#' {
+						continue
+					}
+					visited.Set(r*labyrinth.Cols + c)
+					region = append(region, Position{r, c})
+				}
+			}
+			// 只有玩家的区域不算问题，与 IsConnected 一致
+			for _, p := range region {
+				if !IsPlayerTile(labyrinth.Map[p.Row][p.Col]) {
+					regions = append(regions, region)
+					break
+				}
+			}
+		}
+	}
+	return regions
+}
+
+// FormatReport 校验结果的文本形式
+func FormatReport(report *Report) string {
+	var sb strings.Builder
+	if report.OK() {
+		fmt.Fprintf(&sb, "%s: ok (%dx%d)\n", report.File, report.Rows, report.Cols)
+		return sb.String()
+	}
+	fmt.Fprintf(&sb, "%s: %d problem(s)\n", report.File, len(report.Problems))
+	for _, p := range report.Problems {
+		fmt.Fprintf(&sb, "%s: %s\n", p.Kind, p.Message)
+		if p.Kind == ProblemDisconnected {
+			cells := make([]string, len(p.Cells))
+			for i, c := range p.Cells {
+				cells[i] = fmt.Sprintf("(%d, %d)", c.Row, c.Col)
+			}
+			fmt.Fprintf(&sb, "  cells: %s\n", strings.Join(cells, " "))
+		}
+	}
+	return sb.String()
+}
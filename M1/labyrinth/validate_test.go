@@ -0,0 +1,106 @@
+package labyrinth
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func problemKinds(report *Report) map[string]int {
+	kinds := make(map[string]int)
+	for _, p := range report.Problems {
+		kinds[p.Kind]++
+	}
+	return kinds
+}
+
+// TestValidateMapOK 测试合法地图没有问题
+func TestValidateMapOK(t *testing.T) {
+	report := ValidateMap([]string{"#####", "#0.1#", "#####", "@version 3"})
+	if !report.OK() {
+		t.Errorf("ValidateMap() problems: %+v", report.Problems)
+	}
+	if report.Rows != 3 || report.Cols != 5 {
+		t.Errorf("ValidateMap() rows=%d, cols=%d, expected 3x5", report.Rows, report.Cols)
+	}
+}
+
+// TestValidateMapReportsAll 测试一次报告所有问题
+func TestValidateMapReportsAll(t *testing.T) {
+	report := ValidateMap([]string{
+		"#######",
+		"#0.#..#",
+		"#.~#0#",
+		"###.###",
+		"#.#####",
+		"@bogus",
+	})
+	kinds := problemKinds(report)
+	expected := map[string]int{
+		ProblemRaggedRow:       1,
+		ProblemUnknownChar:     1,
+		ProblemDuplicatePlayer: 1,
+		ProblemMetadata:        1,
+		ProblemDisconnected:    3,
+	}
+	for kind, n := range expected {
+		if kinds[kind] != n {
+			t.Errorf("ValidateMap() %s problems = %d, expected %d (%+v)", kind, kinds[kind], n, report.Problems)
+		}
+	}
+}
+
+// TestDisconnectedRegions 测试列出不连通区域的格子
+func TestDisconnectedRegions(t *testing.T) {
+	lab := newTestLabyrinth(
+		"..#..",
+		"###.#",
+		".#...",
+	)
+	regions := DisconnectedRegions(lab)
+	if len(regions) != 2 {
+		t.Fatalf("DisconnectedRegions() = %v, expected 2 regions", regions)
+	}
+	if len(regions[0]) != 6 || len(regions[1]) != 1 {
+		t.Errorf("DisconnectedRegions() sizes = %d, %d, expected 6, 1", len(regions[0]), len(regions[1]))
+	}
+}
+
+// TestValidateMapTooLarge 测试超大地图
+func TestValidateMapTooLarge(t *testing.T) {
+	lines := make([]string, MaxRows+1)
+	for i := range lines {
+		lines[i] = "."
+	}
+	if kinds := problemKinds(ValidateMap(lines)); kinds[ProblemTooLarge] != 1 {
+		t.Errorf("ValidateMap() should report an oversize map, got %v", kinds)
+	}
+}
+
+// TestValidateFileLongRow 测试超过 64KB 的行报告为地图过大，而不是读取失败
+func TestValidateFileLongRow(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "map.txt")
+	content := strings.Repeat(".", 70000) + "\n" + strings.Repeat(".", 70000) + "\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	report, err := ValidateFile(path)
+	if err != nil {
+		t.Fatalf("ValidateFile() error: %v", err)
+	}
+	if kinds := problemKinds(report); kinds[ProblemTooLarge] != 1 || report.Cols != 70000 {
+		t.Errorf("ValidateFile() cols = %d, problems = %v", report.Cols, kinds)
+	}
+}
+
+// TestValidateMapRaggedRows 测试每一行都与第一行比较宽度，一行过宽不影响后面的行
+func TestValidateMapRaggedRows(t *testing.T) {
+	report := ValidateMap([]string{"#####", "#0.1#####", "#...#", "#####"})
+	if kinds := problemKinds(report); kinds[ProblemRaggedRow] != 1 {
+		t.Errorf("ValidateMap() ragged rows = %d, expected 1 (%+v)", kinds[ProblemRaggedRow], report.Problems)
+	}
+	if report.Cols != 9 {
+		t.Errorf("ValidateMap() cols = %d, expected 9", report.Cols)
+	}
+}
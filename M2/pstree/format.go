@@ -0,0 +1,138 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// 输出格式
+const (
+	FormatText    = "text"
+	FormatJSON    = "json"
+	FormatDOT     = "dot"
+	FormatMermaid = "mermaid"
+)
+
+// TreeJSON --format json 的节点
+type TreeJSON struct {
+	PID      int64        `json:"pid"`
+	PPID     int64        `json:"ppid"`
+	Name     string       `json:"name"`
+	Cmdline  []string     `json:"cmdline,omitempty"`
+	Threads  []ThreadJSON `json:"threads"`
+	Children []*TreeJSON  `json:"children"`
+}
+
+// ThreadJSON --format json 的线程
+type ThreadJSON struct {
+	TID  int64  `json:"tid"`
+	Name string `json:"name"`
+}
+
+// OrderedChildren 按 opts 排列的子进程，显示线程时线程排在子进程之后
+func OrderedChildren(p *Process, opts *Options) []*Process {
+	children := p.Children
+	if opts.ShowThreads {
+		children = append(children[:len(children):len(children)], p.Threads...)
+	}
+	if opts.NumericSort {
+		children = SortByPid(children)
+	}
+	return children
+}
+
+// ToJSON 把子树转换成 JSON 节点，结构化输出不合并相同的子树
+func ToJSON(p *Process, opts *Options) *TreeJSON {
+	node := &TreeJSON{
+		PID:      p.PID,
+		PPID:     p.PPID,
+		Name:     p.Name,
+		Cmdline:  p.Cmdline,
+		Threads:  []ThreadJSON{},
+		Children: []*TreeJSON{},
+	}
+	for _, child := range OrderedChildren(p, opts) {
+		if child.IsThread {
+			node.Threads = append(node.Threads, ThreadJSON{TID: child.PID, Name: child.Name})
+		} else {
+			node.Children = append(node.Children, ToJSON(child, opts))
+		}
+	}
+	return node
+}
+
+// WriteJSON 输出 JSON，只有一个根时是对象，否则是数组（pstree USER）
+func WriteJSON(w io.Writer, roots []*Process, opts *Options) error {
+	var tree any
+	if len(roots) == 1 {
+		tree = ToJSON(roots[0], opts)
+	} else {
+		nodes := []*TreeJSON{}
+		for _, root := range roots {
+			nodes = append(nodes, ToJSON(root, opts))
+		}
+		tree = nodes
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(tree)
+}
+
+// WriteDOT 输出 Graphviz 有向图，线程用虚线连接
+func WriteDOT(w io.Writer, roots []*Process, parentUIDs []int64, opts *Options) error {
+	var sb strings.Builder
+	sb.WriteString("digraph pstree {\n\tnode [shape=box];\n")
+	for i, root := range roots {
+		walkEdges(root, parentUIDs[i], opts, func(p *Process, label string) {
+			style := ""
+			if p.IsThread {
+				style = ", style=dashed"
+			}
+			fmt.Fprintf(&sb, "\t%d [label=%s%s];\n", p.PID, dotQuote(label), style)
+		}, func(parent, child *Process) {
+			style := ""
+			if child.IsThread {
+				style = " [style=dashed]"
+			}
+			fmt.Fprintf(&sb, "\t%d -> %d%s;\n", parent.PID, child.PID, style)
+		})
+	}
+	sb.WriteString("}\n")
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// WriteMermaid 输出 Mermaid flowchart，线程用虚线连接
+func WriteMermaid(w io.Writer, roots []*Process, parentUIDs []int64, opts *Options) error {
+	var sb strings.Builder
+	sb.WriteString("graph TD\n")
+	for i, root := range roots {
+		walkEdges(root, parentUIDs[i], opts, func(p *Process, label string) {
+			fmt.Fprintf(&sb, "\tp%d[\"%s\"]\n", p.PID, strings.ReplaceAll(label, `"`, "#quot;"))
+		}, func(parent, child *Process) {
+			arrow := "-->"
+			if child.IsThread {
+				arrow = "-.->"
+			}
+			fmt.Fprintf(&sb, "\tp%d %s p%d\n", parent.PID, arrow, child.PID)
+		})
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// walkEdges 先序遍历子树，node 收到每个节点与它的显示文本，edge 收到每条父子边
+func walkEdges(p *Process, parentUID int64, opts *Options, node func(*Process, string), edge func(parent, child *Process)) {
+	node(p, Label(p, parentUID, opts))
+	for _, child := range OrderedChildren(p, opts) {
+		edge(p, child)
+		walkEdges(child, p.UID, opts, node, edge)
+	}
+}
+
+// dotQuote DOT 的字符串字面量
+func dotQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
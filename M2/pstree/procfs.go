@@ -0,0 +1,223 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ProcStat /proc/[pid]/stat 的全部字段，顺序与含义见 man 5 proc
+// 旧内核没有的靠后字段保持为 0
+type ProcStat struct {
+	PID                 int64
+	Comm                string // 括号内的名字，可能包含空格与括号
+	State               byte
+	PPID                int64
+	PGRP                int64
+	Session             int64
+	TTYNr               int64
+	TPGID               int64
+	Flags               uint64
+	MinFlt              uint64
+	CMinFlt             uint64
+	MajFlt              uint64
+	CMajFlt             uint64
+	UTime               uint64 // 用户态时间，单位 clock ticks
+	STime               uint64 // 内核态时间，单位 clock ticks
+	CUTime              int64
+	CSTime              int64
+	Priority            int64
+	Nice                int64
+	NumThreads          int64
+	ITRealValue         int64
+	StartTime           uint64 // 系统启动后多少 clock ticks 创建
+	VSize               uint64 // 虚拟内存大小，单位字节
+	RSS                 uint64 // 常驻内存页数
+	RSSLim              uint64
+	StartCode           uint64
+	EndCode             uint64
+	StartStack          uint64
+	KStkESP             uint64
+	KStkEIP             uint64
+	Signal              uint64
+	Blocked             uint64
+	SigIgnore           uint64
+	SigCatch            uint64
+	WChan               uint64
+	NSwap               uint64
+	CNSwap              uint64
+	ExitSignal          int64
+	Processor           int64
+	RTPriority          uint64
+	Policy              uint64
+	DelayAcctBlkioTicks uint64
+	GuestTime           uint64
+	CGuestTime          int64
+	StartData           uint64
+	EndData             uint64
+	StartBrk            uint64
+	ArgStart            uint64
+	ArgEnd              uint64
+	EnvStart            uint64
+	EnvEnd              uint64
+	ExitCode            int64
+}
+
+// ParseProcStat 解析 /proc/[pid]/stat
+// comm 可以包含空格与 ')'，因此以第一个 '(' 与最后一个 ')' 定位 comm
+func ParseProcStat(stat []byte) (*ProcStat, error) {
+	open := bytes.IndexByte(stat, '(')
+	closeParen := bytes.LastIndexByte(stat, ')')
+	if open < 0 || closeParen < open {
+		return nil, errors.New("stat: missing (comm)")
+	}
+	s := &ProcStat{Comm: string(stat[open+1 : closeParen])}
+	pid, err := strconv.ParseInt(string(bytes.TrimSpace(stat[:open])), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("stat: pid: %w", err)
+	}
+	s.PID = pid
+
+	fields := strings.Fields(string(stat[closeParen+1:]))
+	if len(fields) < 2 || len(fields[0]) != 1 {
+		return nil, errors.New("stat: missing state or ppid")
+	}
+	s.State = fields[0][0]
+	// state 之后的字段依次写入
+	targets := []any{
+		&s.PPID, &s.PGRP, &s.Session, &s.TTYNr, &s.TPGID, &s.Flags,
+		&s.MinFlt, &s.CMinFlt, &s.MajFlt, &s.CMajFlt, &s.UTime, &s.STime,
+		&s.CUTime, &s.CSTime, &s.Priority, &s.Nice, &s.NumThreads, &s.ITRealValue,
+		&s.StartTime, &s.VSize, &s.RSS, &s.RSSLim, &s.StartCode, &s.EndCode,
+		&s.StartStack, &s.KStkESP, &s.KStkEIP, &s.Signal, &s.Blocked, &s.SigIgnore,
+		&s.SigCatch, &s.WChan, &s.NSwap, &s.CNSwap, &s.ExitSignal, &s.Processor,
+		&s.RTPriority, &s.Policy, &s.DelayAcctBlkioTicks, &s.GuestTime, &s.CGuestTime, &s.StartData,
+		&s.EndData, &s.StartBrk, &s.ArgStart, &s.ArgEnd, &s.EnvStart, &s.EnvEnd,
+		&s.ExitCode,
+	}
+	for i, field := range fields[1:min(len(fields), len(targets)+1)] {
+		var err error
+		switch target := targets[i].(type) {
+		case *int64:
+			*target, err = strconv.ParseInt(field, 10, 64)
+		case *uint64:
+			*target, err = strconv.ParseUint(field, 10, 64)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("stat: field %d: %w", i+4, err)
+		}
+	}
+	return s, nil
+}
+
+// ProcStatus /proc/[pid]/status 中常用的字段，内存单位为 kB
+// Fields 保留所有行的原始值，包括没有单独解析的字段
+type ProcStatus struct {
+	Name      string
+	State     string
+	Tgid      int64
+	Pid       int64
+	PPid      int64
+	TracerPid int64
+	Uid       [4]int64 // 真实、有效、保存、文件系统 UID
+	Gid       [4]int64
+	Groups    []int64
+	Threads   int64
+	VmPeak    uint64
+	VmSize    uint64
+	VmHWM     uint64
+	VmRSS     uint64
+	VmSwap    uint64
+
+	VoluntaryCtxtSwitches    uint64
+	NonvoluntaryCtxtSwitches uint64
+
+	Fields map[string]string
+}
+
+// ParseProcStatus 解析 /proc/[pid]/status 的 "Key:\tValue" 行
+func ParseProcStatus(status []byte) (*ProcStatus, error) {
+	s := &ProcStatus{Fields: make(map[string]string)}
+	for _, line := range strings.Split(string(status), "\n") {
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		s.Fields[key] = value
+		var err error
+		switch key {
+		case "Name":
+			s.Name = value
+		case "State":
+			s.State = value
+		case "Tgid":
+			s.Tgid, err = strconv.ParseInt(value, 10, 64)
+		case "Pid":
+			s.Pid, err = strconv.ParseInt(value, 10, 64)
+		case "PPid":
+			s.PPid, err = strconv.ParseInt(value, 10, 64)
+		case "TracerPid":
+			s.TracerPid, err = strconv.ParseInt(value, 10, 64)
+		case "Uid":
+			err = parseIDs(value, s.Uid[:])
+		case "Gid":
+			err = parseIDs(value, s.Gid[:])
+		case "Groups":
+			for _, field := range strings.Fields(value) {
+				group, parseErr := strconv.ParseInt(field, 10, 64)
+				if parseErr != nil {
+					err = parseErr
+					break
+				}
+				s.Groups = append(s.Groups, group)
+			}
+		case "Threads":
+			s.Threads, err = strconv.ParseInt(value, 10, 64)
+		case "VmPeak":
+			s.VmPeak, err = parseKB(value)
+		case "VmSize":
+			s.VmSize, err = parseKB(value)
+		case "VmHWM":
+			s.VmHWM, err = parseKB(value)
+		case "VmRSS":
+			s.VmRSS, err = parseKB(value)
+		case "VmSwap":
+			s.VmSwap, err = parseKB(value)
+		case "voluntary_ctxt_switches":
+			s.VoluntaryCtxtSwitches, err = strconv.ParseUint(value, 10, 64)
+		case "nonvoluntary_ctxt_switches":
+			s.NonvoluntaryCtxtSwitches, err = strconv.ParseUint(value, 10, 64)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("status: %s: %w", key, err)
+		}
+	}
+	if _, ok := s.Fields["Uid"]; !ok {
+		return nil, errors.New("status: missing Uid")
+	}
+	return s, nil
+}
+
+// parseIDs 解析 Uid/Gid 行的 4 个 ID
+func parseIDs(value string, ids []int64) error {
+	fields := strings.Fields(value)
+	if len(fields) != len(ids) {
+		return fmt.Errorf("expected %d ids, got %d", len(ids), len(fields))
+	}
+	for i, field := range fields {
+		id, err := strconv.ParseInt(field, 10, 64)
+		if err != nil {
+			return err
+		}
+		ids[i] = id
+	}
+	return nil
+}
+
+// parseKB 解析 "1234 kB"
+func parseKB(value string) (uint64, error) {
+	return strconv.ParseUint(strings.TrimSuffix(value, " kB"), 10, 64)
+}
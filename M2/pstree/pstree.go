@@ -1,13 +1,20 @@
 package main
 
 import (
+	"bytes"
 	"container/list"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"os"
+	"os/user"
 	"sort"
 	"strconv"
 	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
 )
 
 const VersionInfo = "pstree (Go implementation)"
@@ -16,9 +23,32 @@ type Process struct {
 	PID      int64
 	PPID     int64
 	Name     string
+	UID      int64    // /proc/[pid]/status 中的真实 UID，读取失败时为 -1
+	Cmdline  []string // /proc/[pid]/cmdline 按 NUL 分隔的参数，内核线程为空
+	IsThread bool
 	Children []*Process
+	Threads  []*Process // 除主线程外的线程，显示为 {name}
+	Stat     *ProcStat  // 解析后的 /proc/[pid]/stat，虚拟的 0 号进程为 nil
+	Repeat   int        // CompactChildren 合并的相同兄弟子树个数，大于 1 时显示为 N*[...]
 }
 
+// Options 打印选项
+type Options struct {
+	ShowPids    bool
+	NumericSort bool
+	ShowThreads bool
+	Compact     bool // 合并相同的兄弟子树为 N*[name]
+	ShowArgs    bool
+	ArgsWidth   int  // -a 时参数最多显示的字符数，0 表示不截断（-l）
+	ShowUIDs    bool // 与父进程 UID 不同时标出用户
+
+	Highlight map[int64]string // --watch 时按 PID 给节点加上 ANSI 样式
+	Debug     bool             // 输出未处理进程与孤儿进程的数量
+}
+
+// DefaultArgsWidth -a 时命令行参数默认最多显示的字符数，超出部分用 ... 代替，可用 --args-width 修改
+const DefaultArgsWidth = 100
+
 type Node struct {
 	count  int
 	isLast bool
@@ -85,6 +115,27 @@ func main() {
 	showPidsLong := flag.Bool("show-pids", false, "Show PIDs")
 	numericSort := flag.Bool("n", false, "Sort by PID")
 	numericSortLong := flag.Bool("numeric-sort", false, "Sort by PID")
+	hideThreads := flag.Bool("T", false, "Hide threads")
+	hideThreadsLong := flag.Bool("hide-threads", false, "Hide threads")
+	noCompact := flag.Bool("c", false, "Don't compact identical subtrees")
+	noCompactLong := flag.Bool("compact-not", false, "Don't compact identical subtrees")
+	showArgs := flag.Bool("a", false, "Show command line arguments")
+	showArgsLong := flag.Bool("arguments", false, "Show command line arguments")
+	longArgs := flag.Bool("l", false, "Don't truncate command line arguments")
+	longArgsLong := flag.Bool("long", false, "Don't truncate command line arguments")
+	argsWidth := flag.Int("args-width", DefaultArgsWidth, "With -a, truncate arguments to N characters")
+	showUIDs := flag.Bool("u", false, "Show uid transitions")
+	showUIDsLong := flag.Bool("uid-changes", false, "Show uid transitions")
+	showParents := flag.Bool("s", false, "Show parents of the selected process")
+	showParentsLong := flag.Bool("show-parents", false, "Show parents of the selected process")
+	format := flag.String("format", FormatText, "Output format: text, json, dot or mermaid")
+	var watch Interval
+	flag.Var(&watch, "watch", "Redraw the tree every INTERVAL seconds (or a duration like 500ms), highlighting spawned and exited processes")
+	events := flag.Bool("events", false, "With --watch, only print fork/exit events")
+	procRoot := flag.String("proc-root", "", "Read processes from DIR instead of /proc")
+	snapshotFile := flag.String("snapshot", "", "Read processes from a snapshot FILE")
+	saveSnapshot := flag.String("save-snapshot", "", "Save the processes to a snapshot FILE and exit")
+	debug := flag.Bool("debug", false, "Print debug information")
 	version := flag.Bool("V", false, "Show version")
 	versionLong := flag.Bool("version", false, "Show version")
 
@@ -95,34 +146,211 @@ func main() {
 		os.Exit(0)
 	}
 
-	if flag.NArg() > 0 {
-		fmt.Println("Usage: pstree [-p|--show-pids] [-n|--numeric-sort] [-V|--version]")
+	validFormat := *format == FormatText || *format == FormatJSON || *format == FormatDOT || *format == FormatMermaid
+	if *events && watch == 0 {
+		watch = Interval(time.Second)
+	}
+	if flag.NArg() > 1 || !validFormat || watch < 0 || (watch > 0 && *format != FormatText) || (*procRoot != "" && *snapshotFile != "") || *argsWidth < 4 {
+		fmt.Println("Usage: pstree [-p|--show-pids] [-n|--numeric-sort] [-T|--hide-threads] [-c|--compact-not] [-a|--arguments [--args-width N]] [-l|--long] [-u|--uid-changes] [-s|--show-parents] [--format text|json|dot|mermaid] [--watch INTERVAL [--events]] [--proc-root DIR | --snapshot FILE] [--save-snapshot FILE] [--debug] [-V|--version] [PID|USER]")
 		os.Exit(1)
 	}
 
-	showPid := *showPids || *showPidsLong
-	sortByPid := *numericSort || *numericSortLong
+	opts := &Options{
+		ShowPids:    *showPids || *showPidsLong,
+		NumericSort: *numericSort || *numericSortLong,
+		ShowThreads: !*hideThreads && !*hideThreadsLong,
+		ShowArgs:    *showArgs || *showArgsLong,
+		ArgsWidth:   *argsWidth,
+		ShowUIDs:    *showUIDs || *showUIDsLong,
+		Debug:       *debug,
+	}
+	if *longArgs || *longArgsLong {
+		opts.ArgsWidth = 0
+	}
+	// 与 GNU pstree 相同，显示 PID 时每个节点都不同，不再合并
+	opts.Compact = !*noCompact && !*noCompactLong && !opts.ShowPids
 
-	processes, err := ReadProcesses()
-	if err != nil {
-		_, err = fmt.Fprintf(os.Stderr, "Error reading processes: %v\n", err)
+	var source ProcessSource = LiveProc
+	if *procRoot != "" {
+		source = ProcFS{Root: *procRoot}
+	}
+	if *snapshotFile != "" {
+		snapshot, err := LoadSnapshotFile(*snapshotFile)
 		if err != nil {
-			return
+			fmt.Fprintln(os.Stderr, "Error reading snapshot:", err)
+			os.Exit(1)
 		}
+		source = snapshot
+	}
+	if *saveSnapshot != "" {
+		if err := saveSnapshotFile(*saveSnapshot, source); err != nil {
+			fmt.Fprintln(os.Stderr, "Error saving snapshot:", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
+	selection := Selection{Arg: flag.Arg(0), ShowParents: *showParents || *showParentsLong}
+	if watch > 0 {
+		var err error
+		if *events {
+			err = WatchEvents(os.Stdout, time.Duration(watch), source, opts, selection)
+		} else {
+			err = Watch(os.Stdout, time.Duration(watch), source, opts, selection)
+		}
+		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
 	}
 
-	tree := BuildTree(processes)
-	symbolList := Deque{list: list.List{}, m: make(map[int]*Node)}
-	PrintTree(tree.Children[0], 0, &symbolList, showPid, sortByPid, true, true, false)
+	snapshot, err := TakeSnapshot(source, opts, selection)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	roots, parentUIDs := snapshot.Roots, snapshot.ParentUIDs
+	switch *format {
+	case FormatJSON:
+		err = WriteJSON(os.Stdout, roots, opts)
+	case FormatDOT:
+		err = WriteDOT(os.Stdout, roots, parentUIDs, opts)
+	case FormatMermaid:
+		err = WriteMermaid(os.Stdout, roots, parentUIDs, opts)
+	default:
+		PrintRoots(os.Stdout, roots, parentUIDs, opts)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error writing output:", err)
+		os.Exit(1)
+	}
 	os.Exit(0)
 }
 
-// ReadProcesses 读取系统中所有进程信息
-func ReadProcesses() (map[int64]*Process, error) {
+// Selection 命令行选择的子树：PID 或用户名，以及 -s
+type Selection struct {
+	Arg         string
+	ShowParents bool
+}
+
+// Snapshot 一次读取 /proc 得到的进程与选中的子树
+type Snapshot struct {
+	Processes  map[int64]*Process
+	Roots      []*Process
+	ParentUIDs []int64 // 每个根进程的父进程 UID，用于 -u
+}
+
+// saveSnapshotFile 把 source 保存到快照文件
+func saveSnapshotFile(filename string, source ProcessSource) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	if err := WriteSnapshotFile(f, source); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// TakeSnapshot 从 source 读取进程并选出要打印的子树
+func TakeSnapshot(source ProcessSource, opts *Options, selection Selection) (*Snapshot, error) {
+	processes, err := ReadProcesses(source, opts)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading processes: %v", err)
+	}
+	tree := BuildTree(processes)
+	roots, err := SelectRoots(processes, tree, selection.Arg)
+	if err != nil {
+		return nil, err
+	}
+	// 根进程与真实的父进程比较 UID
+	parentUIDs := make([]int64, len(roots))
+	for i, root := range roots {
+		if selection.ShowParents {
+			roots[i] = WithAncestors(processes, root)
+		}
+		parentUIDs[i] = roots[i].UID
+		if parent, ok := processes[roots[i].PPID]; ok && roots[i].PPID != 0 {
+			parentUIDs[i] = parent.UID
+		}
+	}
+	return &Snapshot{Processes: processes, Roots: roots, ParentUIDs: parentUIDs}, nil
+}
+
+// PrintRoots 以文本形式依次把每棵子树写到 w
+func PrintRoots(w io.Writer, roots []*Process, parentUIDs []int64, opts *Options) {
+	for i, root := range roots {
+		symbolList := Deque{list: list.List{}, m: make(map[int]*Node)}
+		PrintTree(w, root, 0, &symbolList, opts, parentUIDs[i], true, true, false, 0)
+		fmt.Fprintln(w)
+	}
+}
+
+// SelectRoots 按命令行参数选择要打印的子树：PID、用户名，为空时是第一个真实的根进程
+func SelectRoots(processes map[int64]*Process, tree *Process, arg string) ([]*Process, error) {
+	if arg == "" {
+		if len(tree.Children) == 0 {
+			return nil, errors.New("No processes found")
+		}
+		return tree.Children[:1], nil
+	}
+	if pid, err := strconv.ParseInt(arg, 10, 64); err == nil {
+		process, ok := processes[pid]
+		if !ok || pid == 0 {
+			return nil, fmt.Errorf("No such process: %d", pid)
+		}
+		return []*Process{process}, nil
+	}
+	u, err := user.Lookup(arg)
+	if err != nil {
+		return nil, fmt.Errorf("No such user name: %s", arg)
+	}
+	uid, err := strconv.ParseInt(u.Uid, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	return FindUserRoots(tree, uid), nil
+}
+
+// FindUserRoots 属于 uid、且父进程不属于 uid 的进程，按 DFS 顺序
+func FindUserRoots(tree *Process, uid int64) []*Process {
+	var roots []*Process
+	var walk func(p *Process, parentUID int64)
+	walk = func(p *Process, parentUID int64) {
+		if p.UID == uid && parentUID != uid {
+			roots = append(roots, p)
+		}
+		for _, child := range p.Children {
+			walk(child, p.UID)
+		}
+	}
+	// 虚拟的 0 号进程不属于任何用户
+	for _, child := range tree.Children {
+		walk(child, -1)
+	}
+	return roots
+}
+
+// WithAncestors 在 p 上面接上一直到根进程的祖先链，祖先只保留通向 p 的那个子进程
+func WithAncestors(processes map[int64]*Process, p *Process) *Process {
+	top := p
+	for top.PID != 0 {
+		parent, ok := processes[top.PPID]
+		if !ok || parent.PID == 0 {
+			break
+		}
+		ancestor := *parent
+		ancestor.Children = []*Process{top}
+		ancestor.Threads = nil
+		top = &ancestor
+	}
+	return top
+}
+
+// ReadProcesses 从 source 读取所有进程信息，按 opts 读取线程与命令行参数
+func ReadProcesses(source ProcessSource, opts *Options) (map[int64]*Process, error) {
 	// 提示：
 	// 1. 遍历 /proc 目录
-	processDirs, err := os.ReadDir("/proc")
+	pids, err := source.PIDs()
 	if err != nil {
 		return nil, err
 	}
@@ -138,13 +366,8 @@ func ReadProcesses() (map[int64]*Process, error) {
 	// 未处理的进程
 	unhandled := make(map[int64]*Process)
 	orphanProcessCount := 0
-	for _, dir := range processDirs {
-		if !dir.IsDir() {
-			continue
-		}
-		pid := dir.Name()
-		statPath := fmt.Sprintf("/proc/%s/stat", pid)
-		stat, err := os.ReadFile(statPath)
+	for _, pid := range pids {
+		stat, err := source.ReadFile(pid, "stat")
 		if err != nil {
 			continue
 		}
@@ -152,6 +375,18 @@ func ReadProcesses() (map[int64]*Process, error) {
 		if err != nil {
 			continue
 		}
+		if process.UID, err = ReadUID(source, process.PID); err != nil {
+			process.UID = -1
+		}
+		if opts.ShowArgs {
+			process.Cmdline = ReadCmdline(source, process.PID)
+		}
+		if opts.ShowThreads {
+			process.Threads = ReadThreads(source, process.PID)
+			for _, thread := range process.Threads {
+				thread.UID = process.UID
+			}
+		}
 		processes[process.PID] = process
 		if _, ok := processes[process.PPID]; ok {
 			processes[process.PPID].Children = append(processes[process.PPID].Children, process)
@@ -159,7 +394,9 @@ func ReadProcesses() (map[int64]*Process, error) {
 			unhandled[process.PID] = process
 		}
 	}
-	fmt.Println("unhandled processes:", len(unhandled))
+	if opts.Debug {
+		fmt.Fprintln(os.Stderr, "unhandled processes:", len(unhandled))
+	}
 	for _, process := range unhandled {
 		if _, ok := processes[process.PPID]; ok {
 			processes[process.PPID].Children = append(processes[process.PPID].Children, process)
@@ -169,27 +406,76 @@ func ReadProcesses() (map[int64]*Process, error) {
 			orphanProcessCount += 1
 		}
 	}
-	fmt.Println("orphanProcessCount:", orphanProcessCount)
+	if opts.Debug {
+		fmt.Fprintln(os.Stderr, "orphanProcessCount:", orphanProcessCount)
+	}
 	// 4. 返回 map[pid]*Process
 	return processes, nil
 }
 
-// ParseStat 解析进程stat
-func ParseStat(stat []byte) (*Process, error) {
-	fields := strings.Fields(string(stat))
-	pid, err := strconv.ParseInt(fields[0], 10, 64)
+// ReadUID 读取 /proc/[pid]/status 中 Uid 行的真实 UID
+func ReadUID(source ProcessSource, pid int64) (int64, error) {
+	status, err := source.ReadFile(pid, "status")
 	if err != nil {
-		return nil, err
+		return -1, err
 	}
-	ppid, err := strconv.ParseInt(fields[3], 10, 64)
+	procStatus, err := ParseProcStatus(status)
+	if err != nil {
+		return -1, fmt.Errorf("/proc/%d/status: %w", pid, err)
+	}
+	return procStatus.Uid[0], nil
+}
+
+// ReadCmdline 读取 /proc/[pid]/cmdline，参数之间以 NUL 分隔
+func ReadCmdline(source ProcessSource, pid int64) []string {
+	cmdline, err := source.ReadFile(pid, "cmdline")
+	if err != nil {
+		return nil
+	}
+	cmdline = bytes.TrimRight(cmdline, "\x00")
+	if len(cmdline) == 0 {
+		return nil
+	}
+	return strings.Split(string(cmdline), "\x00")
+}
+
+// ReadThreads 读取 /proc/[pid]/task/*/stat，跳过与进程 PID 相同的主线程
+func ReadThreads(source ProcessSource, pid int64) []*Process {
+	tids, err := source.TIDs(pid)
+	if err != nil {
+		return nil
+	}
+	var threads []*Process
+	for _, tid := range tids {
+		if tid == pid {
+			continue
+		}
+		stat, err := source.ReadThreadStat(pid, tid)
+		if err != nil {
+			continue
+		}
+		thread, err := ParseStat(stat)
+		if err != nil {
+			continue
+		}
+		thread.PPID = pid
+		thread.IsThread = true
+		threads = append(threads, thread)
+	}
+	return threads
+}
+
+// ParseStat 解析进程stat，字段由 ParseProcStat 解析
+func ParseStat(stat []byte) (*Process, error) {
+	procStat, err := ParseProcStat(stat)
 	if err != nil {
 		return nil, err
 	}
-	name := strings.Trim(fields[1], "()")
 	return &Process{
-		PID:  pid,
-		PPID: ppid,
-		Name: name,
+		PID:  procStat.PID,
+		PPID: procStat.PPID,
+		Name: procStat.Comm,
+		Stat: procStat,
 	}, nil
 }
 
@@ -213,8 +499,114 @@ func SortByPid(processes []*Process) []*Process {
 	return processes
 }
 
+// DisplayName 节点显示的名字，线程用花括号括起来
+func DisplayName(p *Process) string {
+	if p.IsThread {
+		return "{" + p.Name + "}"
+	}
+	return p.Name
+}
+
+// CompactChildren 把名字与形状都相同的兄弟子树合并成一个 Repeat 为 N 的节点，
+// 节点放在第一次出现的位置，子树取第一个；与 GNU pstree 相同，-a 时只合并线程
+func CompactChildren(children []*Process, opts *Options) []*Process {
+	counts := make(map[string]int)
+	keys := make([]string, len(children))
+	for i, child := range children {
+		if opts.ShowArgs && !child.IsThread {
+			continue
+		}
+		keys[i] = SubtreeKey(child, opts)
+		counts[keys[i]]++
+	}
+	var compacted []*Process
+	seen := make(map[string]bool)
+	for i, child := range children {
+		if keys[i] == "" {
+			compacted = append(compacted, child)
+			continue
+		}
+		if seen[keys[i]] {
+			continue
+		}
+		seen[keys[i]] = true
+		if n := counts[keys[i]]; n > 1 {
+			group := *child
+			group.Repeat = n
+			child = &group
+		}
+		compacted = append(compacted, child)
+	}
+	return compacted
+}
+
+// SubtreeKey 子树的规范形式，递归比较名字与形状，不关心兄弟之间的顺序；-u 时还比较 UID
+func SubtreeKey(p *Process, opts *Options) string {
+	children := p.Children
+	if opts.ShowThreads {
+		children = append(children[:len(children):len(children)], p.Threads...)
+	}
+	keys := make([]string, len(children))
+	for i, child := range children {
+		keys[i] = SubtreeKey(child, opts)
+	}
+	sort.Strings(keys)
+	name := strconv.Quote(DisplayName(p)) + opts.Highlight[p.PID]
+	if opts.ShowUIDs && !p.IsThread {
+		name += strconv.FormatInt(p.UID, 10)
+	}
+	return name + "(" + strings.Join(keys, ",") + ")"
+}
+
+// Label 节点的显示文本：名字，-p 的 PID，-u 的用户切换，-a 的命令行参数
+// 合并的节点以 N*[ 开头，对应的 ] 由 PrintTree 在整棵子树的最后一个叶子后补上
+// 与 GNU pstree 相同，-a 时写成 name,pid,uid args，否则写成 name(pid,uid)
+func Label(p *Process, parentUID int64, opts *Options) string {
+	var extra []string
+	if opts.ShowPids {
+		extra = append(extra, strconv.FormatInt(p.PID, 10))
+	}
+	if opts.ShowUIDs && !p.IsThread && p.UID >= 0 && p.UID != parentUID {
+		extra = append(extra, strconv.FormatInt(p.UID, 10))
+	}
+	name := DisplayName(p)
+	if p.Repeat > 1 {
+		name = fmt.Sprintf("%d*[%s", p.Repeat, name)
+	}
+	if !opts.ShowArgs {
+		if len(extra) == 0 {
+			return name
+		}
+		return name + "(" + strings.Join(extra, ",") + ")"
+	}
+	label := strings.Join(append([]string{name}, extra...), ",")
+	if args := FormatArgs(p.Cmdline, opts.ArgsWidth); args != "" && !p.IsThread {
+		label += " " + args
+	}
+	return label
+}
+
+// FormatArgs 去掉 argv[0] 后用空格连接参数，不可打印字符显示为 ?，
+// width 大于 0 时超过 width 个字符的部分截断为 ...
+func FormatArgs(argv []string, width int) string {
+	if len(argv) < 2 {
+		return ""
+	}
+	args := []rune(strings.Map(func(r rune) rune {
+		if !unicode.IsPrint(r) {
+			return '?'
+		}
+		return r
+	}, strings.Join(argv[1:], " ")))
+	if width > 0 && len(args) > width {
+		args = append(args[:max(0, width-3)], []rune("...")...)
+	}
+	return string(args)
+}
+
 // PrintTree 打印进程树，DFS
-func PrintTree(root *Process, prefix int, symbolList *Deque, showPid, isSort, isFront, isTreeStart, isLeafLast bool) {
+// parentUID 是父进程的 UID，用于 -u；closing 是这棵子树最后一个叶子后要补上的 ] 个数
+func PrintTree(w io.Writer, root *Process, prefix int, symbolList *Deque, opts *Options, parentUID int64, isFront, isTreeStart, isLeafLast bool, closing int) {
 	// 提示：
 	// 1. 遍历根进程列表
 	// 2. 打印当前进程（使用 prefix 控制缩进）
@@ -224,47 +616,62 @@ func PrintTree(root *Process, prefix int, symbolList *Deque, showPid, isSort, is
 	prefixSpace := 0
 	newPrefix := 0
 	var text string
-	if showPid {
-		text = fmt.Sprintf("%s(%d)", root.Name, root.PID)
-	} else {
-		text = fmt.Sprintf("%s", root.Name)
+	text = Label(root, parentUID, opts)
+	prefixSpace = utf8.RuneCountInString(text)
+	if style := opts.Highlight[root.PID]; style != "" {
+		text = style + text + ansiReset
 	}
-	prefixSpace = len(text)
 	if isFront {
 		if isTreeStart {
-			fmt.Printf("%s", text)
+			fmt.Fprintf(w, "%s", text)
 		} else {
-			fmt.Printf("%s%s", strings.Repeat("─", 4), text)
+			fmt.Fprintf(w, "%s%s", strings.Repeat("─", 4), text)
 		}
 		newPrefix = prefix + prefixSpace + 4
 	} else {
-		fmt.Println("")
+		fmt.Fprintln(w)
 		//fmt.Println(symbolList.m)
 		for i := 0; i < prefix; i++ {
 			if i == symbolList.GetMax()-2 {
 				if isLeafLast {
-					fmt.Printf("└")
+					fmt.Fprintf(w, "└")
 				} else {
-					fmt.Printf("├")
+					fmt.Fprintf(w, "├")
 				}
 			} else if i > symbolList.GetMax()-2 {
-				fmt.Printf("─")
+				fmt.Fprintf(w, "─")
 			} else if node := symbolList.Get(i + 2); node.count > 0 && node.isLast == false {
-				fmt.Printf("│")
+				fmt.Fprintf(w, "│")
 			} else {
-				fmt.Printf(" ")
+				fmt.Fprintf(w, " ")
 			}
 		}
-		fmt.Printf("%s", text)
+		fmt.Fprintf(w, "%s", text)
 		newPrefix = prefix + prefixSpace + 4
 	}
-	if isSort {
-		root.Children = SortByPid(root.Children)
+	// 与 GNU pstree 相同，-a 时参数很长，子进程都另起一行并缩进 4 列
+	if opts.ShowArgs {
+		newPrefix = prefix + 4
 	}
-	for i, child := range root.Children {
-		isLast := i == len(root.Children)-1
+	children := OrderedChildren(root, opts)
+	if opts.Compact {
+		children = CompactChildren(children, opts)
+	}
+	// 与 GNU pstree 相同，合并组的 ] 跟在组内最后一个叶子后面，整棵子树都在括号里
+	if len(children) == 0 {
+		fmt.Fprint(w, strings.Repeat("]", closing))
+	}
+	for i, child := range children {
+		isLast := i == len(children)-1
+		childClosing := 0
+		if isLast {
+			childClosing = closing
+		}
+		if child.Repeat > 1 {
+			childClosing++
+		}
 		symbolList.PushBack(newPrefix, isLast)
-		PrintTree(child, newPrefix, symbolList, showPid, isSort, i == 0, false, isLast)
+		PrintTree(w, child, newPrefix, symbolList, opts, root.UID, i == 0 && !opts.ShowArgs, false, isLast, childClosing)
 		symbolList.PopBack()
 	}
 }
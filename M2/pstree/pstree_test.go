@@ -1,10 +1,16 @@
 package main
 
 import (
+	"encoding/json"
 	"errors"
+	"fmt"
+	"os"
 	"os/exec"
+	"path/filepath"
+	"strconv"
 	"strings"
 	"testing"
+	"time"
 )
 
 // runPstree 运行 pstree 命令并返回输出和退出码
@@ -21,6 +27,21 @@ func runPstree(args ...string) (string, int, error) {
 	return string(output), exitCode, nil
 }
 
+// runPstreeStdout 运行 pstree 命令并只返回标准输出
+func runPstreeStdout(args ...string) (string, int, error) {
+	cmd := exec.Command("./pstree", args...)
+	output, err := cmd.Output()
+	exitCode := 0
+	if err != nil {
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) {
+			return "", 0, err
+		}
+		exitCode = exitErr.ExitCode()
+	}
+	return string(output), exitCode, nil
+}
+
 // TestBasicNoArgs 测试基本功能（无参数）
 func TestBasicNoArgs(t *testing.T) {
 	output, exitCode, err := runPstree()
@@ -259,3 +280,580 @@ func TestParseStat(t *testing.T) {
 		t.Errorf("Expected name 'init', got '%s'", process.Name)
 	}
 }
+
+func TestParseProcStat(t *testing.T) {
+	tests := []struct {
+		stat string
+		comm string
+	}{
+		{"4242 (Web Content) S 4200 4200 4200 0 -1 4194560 1000 0 3 0 250 75 0 0 20 5 27 0 123456 2147483648 51200 18446744073709551615 1 1 0 0 0 0 0 4096 1260 0 0 0 17 3 0 0 0 0 0 0 0 0 0 0 0 0 0", "Web Content"},
+		{"4242 (a) b) (c) S 4200 4200 4200 0 -1 4194560 1000 0 3 0 250 75 0 0 20 5 27 0 123456 2147483648 51200 18446744073709551615 1 1 0 0 0 0 0 4096 1260 0 0 0 17 3 0 0 0 0 0 0 0 0 0 0 0 0 0\n", "a) b) (c"},
+	}
+	for _, tt := range tests {
+		s, err := ParseProcStat([]byte(tt.stat))
+		if err != nil {
+			t.Fatalf("ParseProcStat(%q) failed: %v", tt.stat, err)
+		}
+		if s.Comm != tt.comm {
+			t.Errorf("Expected comm %q, got %q", tt.comm, s.Comm)
+		}
+		if s.PID != 4242 || s.PPID != 4200 || s.State != 'S' || s.PGRP != 4200 || s.Session != 4200 || s.TPGID != -1 {
+			t.Errorf("Unexpected ids in %+v", s)
+		}
+		if s.UTime != 250 || s.STime != 75 || s.Nice != 5 || s.NumThreads != 27 || s.StartTime != 123456 {
+			t.Errorf("Unexpected times in %+v", s)
+		}
+		if s.VSize != 2147483648 || s.RSS != 51200 || s.RSSLim != 18446744073709551615 || s.ExitSignal != 17 || s.Processor != 3 {
+			t.Errorf("Unexpected memory fields in %+v", s)
+		}
+	}
+
+	for _, stat := range []string{"", "4242 Web Content S 1", "4242 (bash)", "x (bash) S 1", "4242 (bash) S abc"} {
+		if _, err := ParseProcStat([]byte(stat)); err == nil {
+			t.Errorf("Expected error for %q", stat)
+		}
+	}
+
+	// 通过 ParseStat 得到的进程名保留空格
+	process, err := ParseStat([]byte("4242 (Web Content) S 4200 4200 4200 0 -1"))
+	if err != nil || process.Name != "Web Content" || process.PPID != 4200 {
+		t.Errorf("Expected Web Content(4242) under 4200, got %+v, %v", process, err)
+	}
+}
+
+func TestParseProcStatus(t *testing.T) {
+	status := "Name:\tWeb Content\nUmask:\t0022\nState:\tS (sleeping)\nTgid:\t4242\nPid:\t4242\nPPid:\t4200\nTracerPid:\t0\n" +
+		"Uid:\t1000\t1001\t1002\t1003\nGid:\t100\t100\t100\t100\nGroups:\t4 24 27 \n" +
+		"VmPeak:\t 2097152 kB\nVmRSS:\t  204800 kB\nThreads:\t27\nvoluntary_ctxt_switches:\t150\nnonvoluntary_ctxt_switches:\t3\n"
+	s, err := ParseProcStatus([]byte(status))
+	if err != nil {
+		t.Fatalf("ParseProcStatus failed: %v", err)
+	}
+	if s.Name != "Web Content" || s.State != "S (sleeping)" || s.Pid != 4242 || s.PPid != 4200 {
+		t.Errorf("Unexpected process fields in %+v", s)
+	}
+	if s.Uid != [4]int64{1000, 1001, 1002, 1003} || s.Gid != [4]int64{100, 100, 100, 100} {
+		t.Errorf("Unexpected Uid/Gid %v %v", s.Uid, s.Gid)
+	}
+	if len(s.Groups) != 3 || s.Groups[2] != 27 {
+		t.Errorf("Expected groups [4 24 27], got %v", s.Groups)
+	}
+	if s.VmPeak != 2097152 || s.VmRSS != 204800 || s.Threads != 27 || s.VoluntaryCtxtSwitches != 150 || s.NonvoluntaryCtxtSwitches != 3 {
+		t.Errorf("Unexpected counters in %+v", s)
+	}
+	if s.Fields["Umask"] != "0022" {
+		t.Errorf("Expected raw Umask field, got %q", s.Fields["Umask"])
+	}
+
+	for _, status := range []string{"Name:\tbash\n", "Uid:\t1000\n", "Uid:\t0 0 0 0\nPid:\tabc\n"} {
+		if _, err := ParseProcStatus([]byte(status)); err == nil {
+			t.Errorf("Expected error for %q", status)
+		}
+	}
+}
+
+// TestHideThreads 测试 -T 选项
+func TestHideThreads(t *testing.T) {
+	output, exitCode, err := runPstree("-T")
+	if err != nil {
+		t.Fatalf("Failed to run pstree -T: %v", err)
+	}
+
+	if exitCode != 0 {
+		t.Errorf("pstree -T should exit with status 0, got %d", exitCode)
+	}
+
+	if strings.Contains(output, "{") {
+		t.Error("Output should not contain threads")
+	}
+}
+
+// TestReadThreads 测试线程读取，Go 程序至少有几个运行时线程
+func TestReadThreads(t *testing.T) {
+	pid := int64(os.Getpid())
+	threads := ReadThreads(LiveProc, pid)
+	if len(threads) == 0 {
+		t.Fatal("Expected threads for the test process")
+	}
+	for _, thread := range threads {
+		if thread.PID == pid || thread.PPID != pid || !thread.IsThread {
+			t.Errorf("Unexpected thread %+v", thread)
+		}
+	}
+	if name := DisplayName(threads[0]); !strings.HasPrefix(name, "{") || !strings.HasSuffix(name, "}") {
+		t.Errorf("Expected thread name in braces, got '%s'", name)
+	}
+}
+
+// TestCompactChildren 测试相同兄弟子树的合并
+func TestCompactChildren(t *testing.T) {
+	sleep := func(pid int64) *Process { return &Process{PID: pid, Name: "sleep"} }
+	children := []*Process{
+		{PID: 1, Name: "nginx"},
+		{PID: 2, Name: "sh", Children: []*Process{sleep(10)}},
+		{PID: 3, Name: "nginx"},
+		{PID: 4, Name: "sh", Children: []*Process{sleep(11)}},
+		{PID: 5, Name: "sh"},
+		{PID: 6, Name: "nginx", Threads: []*Process{{PID: 12, Name: "nginx", IsThread: true}}},
+	}
+
+	var names []string
+	for _, child := range CompactChildren(children, &Options{ShowThreads: true}) {
+		names = append(names, Label(child, 0, &Options{}))
+	}
+	expected := "2*[nginx,2*[sh,sh,nginx"
+	if got := strings.Join(names, ","); got != expected {
+		t.Errorf("Expected %s, got %s", expected, got)
+	}
+
+	// 不显示线程时最后一个 nginx 与前两个相同
+	names = nil
+	for _, child := range CompactChildren(children, &Options{}) {
+		names = append(names, Label(child, 0, &Options{}))
+	}
+	expected = "3*[nginx,2*[sh,sh"
+	if got := strings.Join(names, ","); got != expected {
+		t.Errorf("Expected %s, got %s", expected, got)
+	}
+
+	threads := []*Process{{PID: 7, Name: "lxcfs", IsThread: true}, {PID: 8, Name: "lxcfs", IsThread: true}}
+	if got := Label(CompactChildren(threads, &Options{ShowThreads: true})[0], 0, &Options{}); got != "2*[{lxcfs}" {
+		t.Errorf("Expected 2*[{lxcfs}, got %s", got)
+	}
+
+}
+
+// TestSelectPid 测试 pstree PID 与 -s 选项
+func TestSelectPid(t *testing.T) {
+	pid := strconv.Itoa(os.Getpid())
+	output, exitCode, err := runPstree("-p", "-s", pid)
+	if err != nil {
+		t.Fatalf("Failed to run pstree -p -s %s: %v", pid, err)
+	}
+
+	if exitCode != 0 {
+		t.Errorf("pstree -p -s PID should exit with status 0, got %d", exitCode)
+	}
+
+	if !strings.Contains(output, "("+pid+")") {
+		t.Errorf("Output should contain the selected PID %s", pid)
+	}
+
+	_, exitCode, _ = runPstree("999999999")
+	if exitCode == 0 {
+		t.Error("pstree with a missing PID should exit with non-zero status")
+	}
+}
+
+// TestFindUserRoots 测试按用户选择子树与祖先链
+func TestFindUserRoots(t *testing.T) {
+	processes := map[int64]*Process{
+		0: {PID: 0, PPID: 0, Name: "init", UID: -1},
+		1: {PID: 1, PPID: 0, Name: "systemd", UID: 0},
+		2: {PID: 2, PPID: 1, Name: "sshd", UID: 0},
+		3: {PID: 3, PPID: 2, Name: "bash", UID: 1000},
+		4: {PID: 4, PPID: 3, Name: "vim", UID: 1000},
+		5: {PID: 5, PPID: 1, Name: "cron", UID: 1000},
+	}
+	for _, p := range processes {
+		if p.PID != 0 {
+			processes[p.PPID].Children = append(processes[p.PPID].Children, p)
+		}
+	}
+	SortByPid(processes[0].Children)
+	SortByPid(processes[1].Children)
+
+	roots := FindUserRoots(processes[0], 1000)
+	if len(roots) != 2 || roots[0].PID != 3 || roots[1].PID != 5 {
+		t.Errorf("Expected roots bash(3) and cron(5), got %v", roots)
+	}
+	if roots := FindUserRoots(processes[0], 0); len(roots) != 1 || roots[0].PID != 1 {
+		t.Errorf("Expected root systemd(1), got %v", roots)
+	}
+
+	top := WithAncestors(processes, processes[3])
+	var chain []int64
+	for p := top; ; p = p.Children[0] {
+		chain = append(chain, p.PID)
+		if p.PID == 3 {
+			break
+		}
+		if len(p.Children) != 1 {
+			t.Fatalf("Ancestor %d should keep only one child, got %d", p.PID, len(p.Children))
+		}
+	}
+	if len(chain) != 3 || chain[0] != 1 || chain[1] != 2 {
+		t.Errorf("Expected ancestors 1,2,3, got %v", chain)
+	}
+	if len(processes[1].Children) != 2 {
+		t.Error("WithAncestors should not modify the tree")
+	}
+}
+
+// TestShowArgs 测试 -a 选项
+func TestShowArgs(t *testing.T) {
+	output, exitCode, err := runPstree("-a", "-l", strconv.Itoa(os.Getpid()))
+	if err != nil {
+		t.Fatalf("Failed to run pstree -a: %v", err)
+	}
+
+	if exitCode != 0 {
+		t.Errorf("pstree -a should exit with status 0, got %d", exitCode)
+	}
+
+	// 测试进程的参数中有 -test.
+	if !strings.Contains(output, "-test.") {
+		t.Errorf("Output should contain the test arguments, got %q", output)
+	}
+
+	// --args-width 修改截断宽度，太小的宽度是参数错误
+	output, exitCode, _ = runPstree("-a", "--args-width", "8", strconv.Itoa(os.Getpid()))
+	if exitCode != 0 || !strings.Contains(output, "...") {
+		t.Errorf("pstree -a --args-width 8 = exit %d, output %q, expected truncated arguments", exitCode, output)
+	}
+	if _, exitCode, _ := runPstree("-a", "--args-width", "2"); exitCode != 1 {
+		t.Errorf("pstree --args-width 2 should exit with status 1, got %d", exitCode)
+	}
+}
+
+// TestLabel 测试 -p、-u 与 -a 的节点文本
+func TestLabel(t *testing.T) {
+	p := &Process{PID: 42, Name: "python3", UID: 0, Cmdline: []string{"/usr/bin/python3", "app.py", "--port\n8080"}}
+	cases := []struct {
+		opts      Options
+		parentUID int64
+		expected  string
+	}{
+		{Options{}, 1000, "python3"},
+		{Options{ShowPids: true}, 0, "python3(42)"},
+		{Options{ShowPids: true, ShowUIDs: true}, 1000, "python3(42,0)"},
+		{Options{ShowUIDs: true}, 0, "python3"},
+		{Options{ShowArgs: true}, 0, "python3 app.py --port?8080"},
+		{Options{ShowArgs: true, ShowPids: true, ShowUIDs: true}, 1000, "python3,42,0 app.py --port?8080"},
+	}
+	for _, c := range cases {
+		if got := Label(p, c.parentUID, &c.opts); got != c.expected {
+			t.Errorf("Label(%+v) = %q, expected %q", c.opts, got, c.expected)
+		}
+	}
+
+	long := []string{"java", strings.Repeat("x", DefaultArgsWidth*2)}
+	for _, width := range []int{DefaultArgsWidth, 20} {
+		if got := FormatArgs(long, width); len(got) != width || !strings.HasSuffix(got, "...") {
+			t.Errorf("FormatArgs() should truncate to %d characters, got %d", width, len(got))
+		}
+	}
+	if got := FormatArgs(long, 0); got != long[1] {
+		t.Error("FormatArgs() with width 0 should not truncate")
+	}
+}
+
+// TestFormatJSON 测试 --format json 输出嵌套的进程树
+func TestFormatJSON(t *testing.T) {
+	output, exitCode, err := runPstreeStdout("--format", "json", strconv.Itoa(os.Getpid()))
+	if err != nil {
+		t.Fatalf("Failed to run pstree --format json: %v", err)
+	}
+
+	if exitCode != 0 {
+		t.Errorf("pstree --format json should exit with status 0, got %d", exitCode)
+	}
+
+	var tree TreeJSON
+	if err := json.Unmarshal([]byte(output), &tree); err != nil {
+		t.Fatalf("Output should be JSON: %v\n%s", err, output)
+	}
+	if tree.PID != int64(os.Getpid()) || len(tree.Threads) == 0 {
+		t.Errorf("Unexpected tree %+v", tree)
+	}
+	if len(tree.Children) != 1 || tree.Children[0].Name != "pstree" {
+		t.Errorf("Expected pstree as the only child, got %+v", tree.Children)
+	}
+}
+
+// TestWriteGraph 测试 DOT 与 Mermaid 的父子边
+func TestWriteGraph(t *testing.T) {
+	root := &Process{PID: 1, Name: `sh "x"`, Children: []*Process{{PID: 2, PPID: 1, Name: "sleep"}}}
+	root.Threads = []*Process{{PID: 3, PPID: 1, Name: "worker", IsThread: true}}
+	opts := &Options{ShowThreads: true}
+
+	var dot strings.Builder
+	if err := WriteDOT(&dot, []*Process{root}, []int64{0}, opts); err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{`1 [label="sh \"x\""];`, "1 -> 2;", "1 -> 3 [style=dashed];", `3 [label="{worker}", style=dashed];`} {
+		if !strings.Contains(dot.String(), expected) {
+			t.Errorf("DOT output should contain %q:\n%s", expected, dot.String())
+		}
+	}
+
+	var mermaid strings.Builder
+	if err := WriteMermaid(&mermaid, []*Process{root}, []int64{0}, opts); err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{"graph TD", `p1["sh #quot;x#quot;"]`, "p1 --> p2", "p1 -.-> p3"} {
+		if !strings.Contains(mermaid.String(), expected) {
+			t.Errorf("Mermaid output should contain %q:\n%s", expected, mermaid.String())
+		}
+	}
+}
+
+// TestDiffProcesses 测试 --watch 前后两帧的比较与退出进程的回接
+func TestDiffProcesses(t *testing.T) {
+	prev := map[int64]*Process{
+		1: {PID: 1, Name: "init"},
+		2: {PID: 2, PPID: 1, Name: "make"},
+		3: {PID: 3, PPID: 2, Name: "cc"},
+		4: {PID: 4, PPID: 3, Name: "as"},
+	}
+	processes := map[int64]*Process{
+		1: {PID: 1, Name: "init"},
+		2: {PID: 2, PPID: 1, Name: "make"},
+		5: {PID: 5, PPID: 2, Name: "ld"},
+	}
+	processes[1].Children = []*Process{processes[2]}
+	processes[2].Children = []*Process{processes[5]}
+
+	spawned, exited := DiffProcesses(prev, VisibleProcesses([]*Process{processes[1]}))
+	if len(spawned) != 1 || spawned[0].PID != 5 {
+		t.Errorf("Expected ld(5) spawned, got %v", spawned)
+	}
+	if len(exited) != 2 || exited[0].PID != 3 || exited[1].PID != 4 {
+		t.Errorf("Expected cc(3) and as(4) exited, got %v", exited)
+	}
+
+	// 退出的 cc 接回 make 下，as 接在 cc 的副本下
+	GraftExited(processes, exited)
+	parent := processes[2]
+	if len(parent.Children) != 2 || parent.Children[1].PID != 3 {
+		t.Fatalf("Expected cc grafted under make, got %v", parent.Children)
+	}
+	if cc := parent.Children[1]; len(cc.Children) != 1 || cc.Children[0].PID != 4 {
+		t.Errorf("Expected as grafted under cc, got %v", cc.Children)
+	}
+	if len(prev[3].Children) != 0 {
+		t.Error("GraftExited should not modify the previous frame")
+	}
+}
+
+func TestDiffProcessesReusedPid(t *testing.T) {
+	prev := map[int64]*Process{
+		7: {PID: 7, Name: "sleep", Stat: &ProcStat{PID: 7, StartTime: 100}},
+	}
+	cur := map[int64]*Process{
+		7: {PID: 7, Name: "curl", Stat: &ProcStat{PID: 7, StartTime: 250}},
+	}
+	spawned, exited := DiffProcesses(prev, cur)
+	if len(spawned) != 1 || spawned[0].Name != "curl" || len(exited) != 1 || exited[0].Name != "sleep" {
+		t.Errorf("Expected sleep(7) replaced by curl(7), got spawned %v exited %v", spawned, exited)
+	}
+
+	cur[7].Stat.StartTime = 100
+	if spawned, exited := DiffProcesses(prev, cur); len(spawned) != 0 || len(exited) != 0 {
+		t.Errorf("Expected no change for the same start time, got spawned %v exited %v", spawned, exited)
+	}
+}
+
+// fixtureProcess 测试用的 proc 树中的一个进程
+type fixtureProcess struct {
+	pid, ppid int64
+	name      string
+	uid       int64
+	threads   []int64
+}
+
+// writeProcFixture 在 dir 下生成只包含 stat、status、cmdline 与 task 的 proc 树
+func writeProcFixture(t *testing.T, dir string, processes []fixtureProcess) {
+	t.Helper()
+	write := func(path, content string) {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	stat := func(pid, ppid int64, name string) string {
+		return fmt.Sprintf("%d (%s) S %d %d %d 0 -1 4194560 0 0 0 0 0 0 0 0 20 0 1 0 100 0 0", pid, name, ppid, pid, pid)
+	}
+	for _, p := range processes {
+		base := filepath.Join(dir, strconv.FormatInt(p.pid, 10))
+		write(filepath.Join(base, "stat"), stat(p.pid, p.ppid, p.name))
+		write(filepath.Join(base, "status"), fmt.Sprintf("Name:\t%s\nPid:\t%d\nPPid:\t%d\nUid:\t%d\t%d\t%d\t%d\n", p.name, p.pid, p.ppid, p.uid, p.uid, p.uid, p.uid))
+		write(filepath.Join(base, "cmdline"), p.name+"\x00--flag\x00")
+		write(filepath.Join(base, "task", strconv.FormatInt(p.pid, 10), "stat"), stat(p.pid, p.ppid, p.name))
+		for _, tid := range p.threads {
+			write(filepath.Join(base, "task", strconv.FormatInt(tid, 10), "stat"), stat(tid, p.ppid, p.name+"-worker"))
+		}
+	}
+}
+
+// TestProcRoot 测试 --proc-root 与快照文件，输出完全确定
+func TestProcRoot(t *testing.T) {
+	dir := t.TempDir()
+	root := filepath.Join(dir, "proc")
+	writeProcFixture(t, root, []fixtureProcess{
+		{pid: 1, ppid: 0, name: "systemd"},
+		{pid: 10, ppid: 1, name: "nginx", threads: []int64{11, 12}},
+		{pid: 20, ppid: 10, name: "nginx", uid: 33},
+		{pid: 21, ppid: 10, name: "nginx", uid: 33},
+		{pid: 30, ppid: 1, name: "cron"},
+		{pid: 40, ppid: 1, name: "sh"},
+		{pid: 41, ppid: 40, name: "sleep"},
+		{pid: 42, ppid: 1, name: "sh"},
+		{pid: 43, ppid: 42, name: "sleep"},
+		{pid: 50, ppid: 1, name: "make"},
+		{pid: 51, ppid: 50, name: "cc"},
+		{pid: 52, ppid: 50, name: "ld"},
+		{pid: 53, ppid: 1, name: "make"},
+		{pid: 54, ppid: 53, name: "cc"},
+		{pid: 55, ppid: 53, name: "ld"},
+	})
+
+	// 合并组的整棵子树都在括号里，] 跟在组内最后一个叶子后面
+	expected := "systemd────nginx────2*[nginx]\n" +
+		"         │        └─2*[{nginx-worker}]\n" +
+		"         ├─cron\n" +
+		"         ├─2*[sh────sleep]\n" +
+		"         └─2*[make────cc\n" +
+		"                    └─ld]\n"
+	output, exitCode, err := runPstreeStdout("--proc-root", root)
+	if err != nil || exitCode != 0 {
+		t.Fatalf("pstree --proc-root failed: %v, exit %d", err, exitCode)
+	}
+	if output != expected {
+		t.Errorf("Unexpected output:\n%s\nexpected:\n%s", output, expected)
+	}
+
+	// 快照保存后读回，输出相同
+	snapshot := filepath.Join(dir, "snapshot.json")
+	if _, exitCode, _ := runPstree("--proc-root", root, "--save-snapshot", snapshot); exitCode != 0 {
+		t.Fatalf("pstree --save-snapshot should exit with status 0, got %d", exitCode)
+	}
+	fromSnapshot, exitCode, err := runPstreeStdout("--snapshot", snapshot)
+	if err != nil || exitCode != 0 {
+		t.Fatalf("pstree --snapshot failed: %v, exit %d", err, exitCode)
+	}
+	if fromSnapshot != output {
+		t.Errorf("Snapshot output differs:\n%s\nexpected:\n%s", fromSnapshot, output)
+	}
+
+	// 调试信息只在 --debug 时输出
+	if strings.Contains(output, "orphanProcessCount") {
+		t.Error("Debug output should be hidden without --debug")
+	}
+	debugOutput, _, _ := runPstree("--proc-root", root, "--debug")
+	if !strings.Contains(debugOutput, "orphanProcessCount") {
+		t.Error("Debug output should be shown with --debug")
+	}
+
+	// 用户选择使用 status 中的 Uid
+	processes, err := ReadProcesses(ProcFS{Root: root}, &Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if roots := FindUserRoots(BuildTree(processes), 33); len(roots) != 2 || roots[0].PID != 20 {
+		t.Errorf("Expected the two nginx workers, got %v", roots)
+	}
+}
+
+// TestEmptySource 测试没有任何进程的来源报错而不是崩溃
+func TestEmptySource(t *testing.T) {
+	dir := t.TempDir()
+	empty := filepath.Join(dir, "proc")
+	if err := os.Mkdir(empty, 0755); err != nil {
+		t.Fatal(err)
+	}
+	snapshot := filepath.Join(dir, "snapshot.json")
+	if err := os.WriteFile(snapshot, []byte(`{"processes":[]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, args := range [][]string{{"--proc-root", empty}, {"--snapshot", snapshot}} {
+		output, exitCode, _ := runPstree(args...)
+		if exitCode != 1 || !strings.Contains(output, "No processes found") {
+			t.Errorf("pstree %v = exit %d, output %q, expected No processes found", args, exitCode, output)
+		}
+	}
+}
+
+// steppedSource 每次读取进程列表前执行一步修改，步骤用完后返回错误让 Watch 退出
+type steppedSource struct {
+	ProcFS
+	steps []func()
+}
+
+func (s *steppedSource) PIDs() ([]int64, error) {
+	if len(s.steps) == 0 {
+		return nil, errors.New("no more frames")
+	}
+	s.steps[0]()
+	s.steps = s.steps[1:]
+	return s.ProcFS.PIDs()
+}
+
+// TestWatch 测试 --watch 每帧重绘，新进程高亮、退出的进程带删除线
+func TestWatch(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "proc")
+	writeProcFixture(t, root, []fixtureProcess{
+		{pid: 1, ppid: 0, name: "systemd"},
+		{pid: 40, ppid: 1, name: "sh"},
+		{pid: 41, ppid: 40, name: "sleep"},
+	})
+	source := &steppedSource{ProcFS: ProcFS{Root: root}, steps: []func(){
+		func() {},
+		func() {
+			if err := os.RemoveAll(filepath.Join(root, "41")); err != nil {
+				t.Fatal(err)
+			}
+			writeProcFixture(t, root, []fixtureProcess{{pid: 44, ppid: 40, name: "cat"}})
+		},
+	}}
+
+	var out strings.Builder
+	err := Watch(&out, time.Millisecond, source, &Options{ShowPids: true}, Selection{})
+	if err == nil || !strings.Contains(err.Error(), "no more frames") {
+		t.Fatalf("Watch() error = %v, expected the source error", err)
+	}
+	frames := strings.Split(out.String(), ansiClear)
+	if len(frames) != 3 || frames[0] != "" {
+		t.Fatalf("Expected two frames, got %q", out.String())
+	}
+	if !strings.Contains(frames[1], "sh(40)────sleep(41)") || strings.Contains(frames[1], "\033[") {
+		t.Errorf("First frame should show the tree without highlights:\n%s", frames[1])
+	}
+	if !strings.Contains(frames[2], ansiSpawned+"cat(44)"+ansiReset) || !strings.Contains(frames[2], ansiExited+"sleep(41)"+ansiReset) {
+		t.Errorf("Second frame should highlight the spawned and exited processes:\n%q", frames[2])
+	}
+}
+
+// TestWatchInterval 测试 --watch 接受纯秒数与 Go 时长
+func TestWatchInterval(t *testing.T) {
+	tests := map[string]time.Duration{
+		"2":     2 * time.Second,
+		"0.5":   500 * time.Millisecond,
+		"500ms": 500 * time.Millisecond,
+		"1m":    time.Minute,
+	}
+	for value, expected := range tests {
+		var interval Interval
+		if err := interval.Set(value); err != nil || time.Duration(interval) != expected {
+			t.Errorf("Interval.Set(%q) = %v, %v, expected %v", value, time.Duration(interval), err, expected)
+		}
+	}
+	for _, value := range []string{"", "2x", "NaN", "Inf"} {
+		var interval Interval
+		if err := interval.Set(value); err == nil {
+			t.Errorf("Interval.Set(%q) should fail", value)
+		}
+	}
+
+	// --watch 2 通过参数解析，开始读取进程后才因为空快照退出
+	snapshot := filepath.Join(t.TempDir(), "snapshot.json")
+	if err := os.WriteFile(snapshot, []byte(`{"processes":[]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	output, exitCode, _ := runPstree("--watch", "2", "--snapshot", snapshot)
+	if exitCode != 1 || !strings.Contains(output, "No processes found") {
+		t.Errorf("pstree --watch 2 = exit %d, output %q, expected No processes found", exitCode, output)
+	}
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+)
+
+// ProcessSource 进程信息的来源：实时的 /proc、其他目录下的 proc 树或保存的快照
+// 各方法返回的内容与 /proc 中对应文件相同，由 ReadProcesses 解析
+type ProcessSource interface {
+	// PIDs 所有进程的 PID
+	PIDs() ([]int64, error)
+	// ReadFile 读取 /proc/[pid]/name，name 为 stat、status 或 cmdline
+	ReadFile(pid int64, name string) ([]byte, error)
+	// TIDs 进程的所有线程，包含主线程
+	TIDs(pid int64) ([]int64, error)
+	// ReadThreadStat 读取 /proc/[pid]/task/[tid]/stat
+	ReadThreadStat(pid, tid int64) ([]byte, error)
+}
+
+// ProcFS 以 Root 为根的 proc 文件系统，Root 为 /proc 时就是当前系统
+type ProcFS struct {
+	Root string
+}
+
+// LiveProc 当前系统的 /proc
+var LiveProc = ProcFS{Root: "/proc"}
+
+// PIDs 按目录顺序返回数字命名的目录
+func (p ProcFS) PIDs() ([]int64, error) {
+	return numericDirs(p.Root)
+}
+
+func (p ProcFS) ReadFile(pid int64, name string) ([]byte, error) {
+	return os.ReadFile(filepath.Join(p.Root, strconv.FormatInt(pid, 10), name))
+}
+
+func (p ProcFS) TIDs(pid int64) ([]int64, error) {
+	return numericDirs(filepath.Join(p.Root, strconv.FormatInt(pid, 10), "task"))
+}
+
+func (p ProcFS) ReadThreadStat(pid, tid int64) ([]byte, error) {
+	return os.ReadFile(filepath.Join(p.Root, strconv.FormatInt(pid, 10), "task", strconv.FormatInt(tid, 10), "stat"))
+}
+
+// numericDirs 目录下名字是数字的子目录
+func numericDirs(dir string) ([]int64, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var ids []int64
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		if id, err := strconv.ParseInt(entry.Name(), 10, 64); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+// SnapshotProcess 快照文件中的一个进程，保存 /proc 中的原始内容
+type SnapshotProcess struct {
+	PID     int64            `json:"pid"`
+	Stat    string           `json:"stat"`
+	Status  string           `json:"status,omitempty"`
+	Cmdline string           `json:"cmdline,omitempty"`
+	Threads map[int64]string `json:"threads,omitempty"` // tid -> task/[tid]/stat
+}
+
+// SnapshotFile 从 --save-snapshot 保存的文件中读取进程，保持保存时的顺序
+type SnapshotFile struct {
+	Processes []*SnapshotProcess `json:"processes"`
+
+	byPID map[int64]*SnapshotProcess
+}
+
+// LoadSnapshotFile 读取快照文件
+func LoadSnapshotFile(filename string) (*SnapshotFile, error) {
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	snapshot := &SnapshotFile{}
+	if err := json.Unmarshal(content, snapshot); err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	snapshot.byPID = make(map[int64]*SnapshotProcess, len(snapshot.Processes))
+	for _, p := range snapshot.Processes {
+		snapshot.byPID[p.PID] = p
+	}
+	return snapshot, nil
+}
+
+// WriteSnapshotFile 把 source 中的所有进程保存为快照，读不到的进程（已退出）跳过
+func WriteSnapshotFile(w io.Writer, source ProcessSource) error {
+	pids, err := source.PIDs()
+	if err != nil {
+		return err
+	}
+	snapshot := SnapshotFile{Processes: []*SnapshotProcess{}}
+	for _, pid := range pids {
+		stat, err := source.ReadFile(pid, "stat")
+		if err != nil {
+			continue
+		}
+		p := &SnapshotProcess{PID: pid, Stat: string(stat)}
+		if status, err := source.ReadFile(pid, "status"); err == nil {
+			p.Status = string(status)
+		}
+		if cmdline, err := source.ReadFile(pid, "cmdline"); err == nil {
+			p.Cmdline = string(cmdline)
+		}
+		tids, _ := source.TIDs(pid)
+		for _, tid := range tids {
+			if stat, err := source.ReadThreadStat(pid, tid); err == nil {
+				if p.Threads == nil {
+					p.Threads = make(map[int64]string)
+				}
+				p.Threads[tid] = string(stat)
+			}
+		}
+		snapshot.Processes = append(snapshot.Processes, p)
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(snapshot)
+}
+
+func (s *SnapshotFile) PIDs() ([]int64, error) {
+	pids := make([]int64, len(s.Processes))
+	for i, p := range s.Processes {
+		pids[i] = p.PID
+	}
+	return pids, nil
+}
+
+func (s *SnapshotFile) ReadFile(pid int64, name string) ([]byte, error) {
+	p, ok := s.byPID[pid]
+	if !ok {
+		return nil, fs.ErrNotExist
+	}
+	var content string
+	switch name {
+	case "stat":
+		content = p.Stat
+	case "status":
+		content = p.Status
+	case "cmdline":
+		content = p.Cmdline
+	default:
+		return nil, fs.ErrNotExist
+	}
+	return []byte(content), nil
+}
+
+func (s *SnapshotFile) TIDs(pid int64) ([]int64, error) {
+	p, ok := s.byPID[pid]
+	if !ok {
+		return nil, fs.ErrNotExist
+	}
+	tids := make([]int64, 0, len(p.Threads))
+	for tid := range p.Threads {
+		tids = append(tids, tid)
+	}
+	slices.Sort(tids)
+	return tids, nil
+}
+
+func (s *SnapshotFile) ReadThreadStat(pid, tid int64) ([]byte, error) {
+	p, ok := s.byPID[pid]
+	if !ok {
+		return nil, fs.ErrNotExist
+	}
+	stat, ok := p.Threads[tid]
+	if !ok {
+		return nil, fs.ErrNotExist
+	}
+	return []byte(stat), nil
+}
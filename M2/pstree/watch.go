@@ -0,0 +1,160 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"sort"
+	"strconv"
+	"time"
+)
+
+const (
+	ansiClear   = "\033[H\033[2J"
+	ansiSpawned = "\033[32m" // 新出现的进程：绿色
+	ansiExited  = "\033[9m"  // 刚退出的进程：删除线
+	ansiReset   = "\033[0m"
+)
+
+// EventTimeFormat --events 每行开头的时间戳
+const EventTimeFormat = "2006-01-02 15:04:05.000"
+
+// Interval --watch 的间隔：纯数字按秒计算（与 watch -n 相同），也接受 500ms、1m 这样的 Go 时长
+type Interval time.Duration
+
+func (i *Interval) String() string {
+	return time.Duration(*i).String()
+}
+
+func (i *Interval) Set(s string) error {
+	if seconds, err := strconv.ParseFloat(s, 64); err == nil && !math.IsNaN(seconds) && !math.IsInf(seconds, 0) {
+		*i = Interval(seconds * float64(time.Second))
+		return nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return fmt.Errorf("invalid interval %q", s)
+	}
+	*i = Interval(d)
+	return nil
+}
+
+// Watch 每隔 interval 重新读取 /proc 并在 w 上原地重绘进程树，
+// 新出现的进程显示为绿色，退出的进程带删除线显示一帧；只在出错时返回
+func Watch(w io.Writer, interval time.Duration, source ProcessSource, opts *Options, selection Selection) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	var prev map[int64]*Process
+	for ; ; <-ticker.C {
+		snapshot, err := TakeSnapshot(source, opts, selection)
+		if err != nil {
+			return err
+		}
+		visible := VisibleProcesses(snapshot.Roots)
+		opts.Highlight = make(map[int64]string)
+		if prev != nil {
+			spawned, exited := DiffProcesses(prev, visible)
+			for _, p := range spawned {
+				opts.Highlight[p.PID] = ansiSpawned
+			}
+			// PID 被复用时高亮按 PID 区分不了新旧进程，只显示新进程
+			exited = slices.DeleteFunc(exited, func(p *Process) bool { return visible[p.PID] != nil })
+			for _, p := range exited {
+				opts.Highlight[p.PID] = ansiExited
+			}
+			GraftExited(snapshot.Processes, exited)
+		}
+		prev = visible
+
+		fmt.Fprint(w, ansiClear)
+		fmt.Fprintf(w, "Every %s: pstree\t%s\n\n", interval, time.Now().Format(time.DateTime))
+		PrintRoots(w, snapshot.Roots, snapshot.ParentUIDs, opts)
+	}
+}
+
+// WatchEvents 每隔 interval 重新读取 /proc，只输出进程的创建与退出；只在出错时返回
+func WatchEvents(w io.Writer, interval time.Duration, source ProcessSource, opts *Options, selection Selection) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	var prev map[int64]*Process
+	for ; ; <-ticker.C {
+		snapshot, err := TakeSnapshot(source, opts, selection)
+		if err != nil {
+			return err
+		}
+		visible := VisibleProcesses(snapshot.Roots)
+		if prev != nil {
+			spawned, exited := DiffProcesses(prev, visible)
+			now := time.Now().Format(EventTimeFormat)
+			for _, p := range spawned {
+				fmt.Fprintf(w, "%s fork %d (%s) ppid %d\n", now, p.PID, p.Name, p.PPID)
+			}
+			for _, p := range exited {
+				fmt.Fprintf(w, "%s exit %d (%s) ppid %d\n", now, p.PID, p.Name, p.PPID)
+			}
+		}
+		prev = visible
+	}
+}
+
+// VisibleProcesses 选中的子树中的所有进程（不含线程）
+func VisibleProcesses(roots []*Process) map[int64]*Process {
+	visible := make(map[int64]*Process)
+	var walk func(p *Process)
+	walk = func(p *Process) {
+		visible[p.PID] = p
+		for _, child := range p.Children {
+			walk(child)
+		}
+	}
+	for _, root := range roots {
+		walk(root)
+	}
+	return visible
+}
+
+// DiffProcesses 比较前后两帧，返回新出现与已经退出的进程，按 PID 排序
+// PID 相同但 starttime 不同说明 PID 被复用，视为旧进程退出、新进程创建
+func DiffProcesses(prev, cur map[int64]*Process) (spawned, exited []*Process) {
+	for pid, p := range cur {
+		if old, ok := prev[pid]; !ok || !sameProcess(old, p) {
+			spawned = append(spawned, p)
+		}
+	}
+	for pid, p := range prev {
+		if now, ok := cur[pid]; !ok || !sameProcess(p, now) {
+			exited = append(exited, p)
+		}
+	}
+	sort.Slice(spawned, func(i, j int) bool { return spawned[i].PID < spawned[j].PID })
+	sort.Slice(exited, func(i, j int) bool { return exited[i].PID < exited[j].PID })
+	return spawned, exited
+}
+
+// sameProcess 两帧中 PID 相同的进程是否为同一个进程
+func sameProcess(a, b *Process) bool {
+	if a.Stat == nil || b.Stat == nil {
+		return true
+	}
+	return a.Stat.StartTime == b.Stat.StartTime
+}
+
+// GraftExited 把退出的进程（不带子树）接回到原来的父进程下，父进程也已退出时接在父进程的副本下
+func GraftExited(processes map[int64]*Process, exited []*Process) {
+	grafts := make(map[int64]*Process, len(exited))
+	for _, p := range exited {
+		graft := *p
+		graft.Children, graft.Threads = nil, nil
+		grafts[p.PID] = &graft
+	}
+	for _, p := range exited {
+		parent, ok := grafts[p.PPID]
+		if !ok {
+			parent, ok = processes[p.PPID]
+		}
+		if ok {
+			parent.Children = append(parent.Children, grafts[p.PID])
+		}
+	}
+}